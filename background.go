@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// backgroundWriters keeps overwriting random files while a read pattern is
+// measured. Files are rewritten in place at their own size, so concurrent
+// readers never see one shortened.
+type backgroundWriters struct {
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	writes int64
+	bytes  int64
+
+	mu  sync.Mutex
+	err error
+}
+
+func startBackgroundWriters(files []FileInfo, n int, rng *rand.Rand, fsync bool) *backgroundWriters {
+	b := &backgroundWriters{stop: make(chan struct{})}
+	var maxSize int64
+	for _, file := range files {
+		maxSize = max(maxSize, file.Size)
+	}
+	for w := 0; w < n; w++ {
+		// Each writer gets its own generator, a *rand.Rand isn't safe to
+		// share between goroutines
+		writerRng := rand.New(rand.NewSource(rng.Int63()))
+		data := make([]byte, maxSize)
+		writerRng.Read(data)
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.run(files, writerRng, data, fsync)
+		}()
+	}
+	return b
+}
+
+func (b *backgroundWriters) run(files []FileInfo, rng *rand.Rand, data []byte, fsync bool) {
+	for {
+		select {
+		case <-b.stop:
+			return
+		default:
+		}
+		file := files[rng.Intn(len(files))]
+		err := overwriteFile(file, data, fsync)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			b.mu.Lock()
+			if b.err == nil {
+				b.err = err
+			}
+			b.mu.Unlock()
+			return
+		}
+		atomic.AddInt64(&b.writes, 1)
+		atomic.AddInt64(&b.bytes, file.Size)
+	}
+}
+
+// overwriteFile writes over the first file.Size bytes of the file without
+// truncating it first
+func overwriteFile(file FileInfo, data []byte, fsync bool) error {
+	f, err := os.OpenFile(file.Path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data[:file.Size], 0); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to overwrite file %s: %w", file.Path, err)
+	}
+	if fsync {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to sync file %s: %w", file.Path, err)
+		}
+	}
+	return f.Close()
+}
+
+// finish stops the writers and returns what they wrote and the first error
+// that stopped one of them
+func (b *backgroundWriters) finish() (writes, bytes int64, err error) {
+	close(b.stop)
+	b.wg.Wait()
+	return b.writes, b.bytes, b.err
+}
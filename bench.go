@@ -1,315 +1,4422 @@
 package main
 
 import (
+	"bufio"
+	"compress/gzip"
+	"container/list"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"math"
 	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unsafe"
 )
 
+// configVersion is the config schema this binary expects. Bump it when new
+// fields change what an existing config file would run.
+const configVersion = 2
+
 type BenchmarkConfig struct {
-	NumFiles        int    `json:"numFiles"`
-	FileSizeKB      int    `json:"fileSizeKB"`
-	ReadPatterns    []int  `json:"readPatterns"`
-	TargetDirectory string `json:"targetDirectory"`
-	Iterations      int    `json:"iterations"`
+	Version         int           `json:"version"`
+	NumFiles        int           `json:"numFiles"`
+	FileSizeKB      int           `json:"fileSizeKB"`
+	ReadPatterns    []PatternSpec `json:"readPatterns"`
+	TargetDirectory DirList       `json:"targetDirectory"`
+	Iterations      int           `json:"iterations"`
+	Seed            int64         `json:"seed"`
+	Concurrency     int           `json:"concurrency"`
+	MaxOpenFiles    int           `json:"maxOpenFiles"`
+	DropCache       bool          `json:"dropCache"`
+	WriteNewFiles   bool          `json:"writeNewFiles"`
+	BlockSizeKB     int           `json:"blockSizeKB"`
+	BlockOffsets    string        `json:"blockOffsets"`
+	GaussianStdDev  float64       `json:"gaussianStdDev"`
+	GaussianDrift   float64       `json:"gaussianDrift"`
+	Warmup          int           `json:"warmup"`
+	Backend         string        `json:"backend"`
+	BufSizeKB       int           `json:"bufSizeKB,omitempty"`
+	QueueDepth      int           `json:"queueDepth,omitempty"`
+	IovecKB         int           `json:"iovecKB,omitempty"`
+	HotSetPercent   float64       `json:"hotSetPercent"`
+	HotSetHitRate   float64       `json:"hotSetHitRate"`
+	ZipfS           float64       `json:"zipfS"`
+	ZipfV           float64       `json:"zipfV"`
+	RecencyDecay    float64       `json:"recencyDecay"`
+	Stride          int           `json:"stride"`
+	ReadsPerFile    int           `json:"readsPerFile"`
+	RepeatMode      string        `json:"repeatMode"`
+
+	// The Bimodal pattern reads from one of two hot sets, each a [start,
+	// end) range in percent of the file set, and switches between them with
+	// probability BimodalSwitch per access. A BimodalCold fraction of
+	// accesses go to a uniformly random file instead.
+	BimodalSetA   [2]float64 `json:"bimodalSetA"`
+	BimodalSetB   [2]float64 `json:"bimodalSetB"`
+	BimodalSwitch float64    `json:"bimodalSwitch"`
+	BimodalCold   float64    `json:"bimodalCold"`
+
+	// SampleSize limits each iteration to this many operations, taken as
+	// a window of the pattern's order over the full file set starting at
+	// a random position (0 makes one pass over the whole order)
+	SampleSize int `json:"sampleSize"`
+
+	// TraceFile is the access log the Trace Replay pattern follows, one
+	// file index or file name per line. traceOrder holds it once loaded.
+	TraceFile  string `json:"traceFile,omitempty"`
+	traceOrder []int
+
+	// ZipfPermute shuffles which file each Zipfian rank maps to, so the hot
+	// files are spread over the dataset instead of being the lowest
+	// indices. The shuffle is seeded from the run's seed, zipfSeed, and
+	// stays the same for every iteration.
+	ZipfPermute bool `json:"zipfPermute"`
+	zipfSeed    int64
+
+	// ThroughputWindowMs buckets completed bytes into windows of this many
+	// milliseconds to record throughput over time (0 disables it)
+	ThroughputWindowMs int `json:"throughputWindowMs"`
+
+	// Duration, when set, runs each iteration for this long (looping over
+	// the access order) instead of making exactly one pass through it
+	Duration string `json:"duration"`
+
+	// ThinkTime pauses each reader for this long between successive
+	// operations. The pauses are left out of the measured duration.
+	ThinkTime string `json:"thinkTime"`
+
+	// BurstSize issues operations in bursts of this many, waiting for each
+	// burst to complete and then idling for IdleGap. The gaps are left out
+	// of the measured duration like think time.
+	BurstSize int    `json:"burstSize"`
+	IdleGap   string `json:"idleGap"`
+
+	// ReadTimeout gives up on an operation that takes longer than this and
+	// counts it as timed out instead of letting a hung read stall the run
+	ReadTimeout string `json:"readTimeout"`
+
+	// MaxRetries retries a failed operation up to this many times before
+	// counting it as an error, waiting RetryBackoff before the first retry
+	// and twice as long before each one after that (default "10ms")
+	MaxRetries   int    `json:"maxRetries"`
+	RetryBackoff string `json:"retryBackoff,omitempty"`
+
+	// Patterns share one dataset, so a pattern can be served from cache
+	// warmed by the ones before it. Isolation resets that state between
+	// patterns ("none", "dropcache" or "regenerate") and ShufflePatterns
+	// randomizes the order patterns run in.
+	Isolation       string `json:"isolation"`
+	ShufflePatterns bool   `json:"shufflePatterns"`
+
+	// Runs repeats each directory's whole suite this many times over the
+	// same dataset and aggregates every pattern across the runs.
+	// RunIsolation resets state between runs the way Isolation does
+	// between patterns.
+	Runs         int    `json:"runs"`
+	RunIsolation string `json:"runIsolation"`
+
+	// File size distribution: "fixed" (FileSizeKB), "uniform" between
+	// MinFileSizeKB and MaxFileSizeKB, or "lognormal" with mean FileSizeKB
+	SizeDistribution string  `json:"sizeDistribution"`
+	MinFileSizeKB    int     `json:"minFileSizeKB"`
+	MaxFileSizeKB    int     `json:"maxFileSizeKB"`
+	SizeSigma        float64 `json:"sizeSigma"`
+
+	// CacheSizeFiles simulates an LRU cache holding this many files
+	// alongside the reads and reports its hit ratio (0 disables it)
+	CacheSizeFiles int `json:"cacheSizeFiles"`
+
+	// Verify checks every read against the checksum recorded when the file
+	// was written. Hashing is part of the timed read.
+	Verify bool `json:"verify"`
+
+	// Compressibility is the fraction of generated content that is a
+	// repeated byte rather than random data, from 0 (incompressible) to 1
+	Compressibility float64 `json:"compressibility"`
+
+	// ZeroContent fills files with a repeating byte pattern instead of
+	// random data, which makes generating them much faster. The data
+	// compresses to almost nothing, so it says nothing about compression.
+	ZeroContent bool `json:"zeroContent"`
+
+	// FileMode is the octal permission string generated files get, setuid,
+	// setgid and sticky bits included (default "0644")
+	FileMode string `json:"fileMode"`
+
+	// Sparse creates every file by truncating it to size instead of
+	// writing it, so it occupies next to no disk and reads back as zeros
+	Sparse bool `json:"sparse"`
+
+	// Tmpfs puts every target directory on a tmpfs, mounting one if it
+	// isn't already, so the numbers show software overhead without a disk
+	// behind it (Linux only)
+	Tmpfs bool `json:"tmpfs"`
+
+	// WriteRatio is the fraction of Mixed pattern operations that overwrite
+	// the selected file instead of reading it
+	WriteRatio float64 `json:"writeRatio"`
+
+	// BackgroundWriters overwrite random files for as long as each read
+	// pattern is measured. Every such pattern is first run without them
+	// as a baseline.
+	BackgroundWriters int `json:"backgroundWriters"`
+
+	// SweepWorkers runs every pattern once per listed concurrency level
+	// instead of only at Concurrency
+	SweepWorkers []int `json:"sweepWorkers,omitempty"`
+
+	// DirDepth nests files DirDepth directories deep with at most
+	// FilesPerDir entries per directory (0 keeps every file in one level)
+	DirDepth    int `json:"dirDepth"`
+	FilesPerDir int `json:"filesPerDir"`
+
+	// Fsync syncs every written file before the write is timed as done and
+	// ODirect opens written files with O_DIRECT, bypassing the page cache
+	Fsync   bool `json:"fsync"`
+	ODirect bool `json:"odirect"`
+
+	// FadviseSequential marks every file the Sequential and Strided
+	// patterns read with POSIX_FADV_SEQUENTIAL so the kernel reads ahead
+	// further (Linux only, read backend)
+	FadviseSequential bool `json:"fadviseSequential"`
+
+	// PhaseTiming times the open, stat, read and close of every whole-file
+	// read separately and reports the mean of each phase
+	PhaseTiming bool `json:"phaseTiming"`
+
+	// ReadFraction reads only this leading fraction of each file, like a
+	// header or thumbnail lookup (0 reads whole files)
+	ReadFraction float64 `json:"readFraction"`
+
+	// BlockAlign rounds block read offsets down to a multiple of this many
+	// bytes, BlockMisalign then shifts each one half a boundary off it.
+	// CompareAlignment measures every block read pattern aligned and again
+	// misaligned.
+	BlockAlign       int  `json:"blockAlign,omitempty"`
+	BlockMisalign    bool `json:"blockMisalign,omitempty"`
+	CompareAlignment bool `json:"compareAlignment,omitempty"`
+
+	// CountSyscalls counts the syscalls the measured iterations' operations
+	// make, by kind
+	CountSyscalls bool `json:"countSyscalls"`
+
+	// Streaming expands ReadsPerFile repeats on the fly and keeps latencies
+	// in a histogram, so repeats and latencies take no memory per read. Each
+	// pass's base order is still built in full, one index per access.
+	// Percentiles are then accurate to within 1%.
+	Streaming bool `json:"streaming"`
+
+	// MissingRatio deletes this fraction of the files after they're
+	// generated. Reads of missing files are counted rather than failing.
+	MissingRatio float64 `json:"missingRatio"`
+
+	// Auto replaces the fixed Iterations: each pattern runs at least
+	// MinIterations and stops once the coefficient of variation of its last
+	// MinIterations durations drops below TargetCV, or at MaxIterations
+	Auto          bool    `json:"auto"`
+	MinIterations int     `json:"minIterations,omitempty"`
+	MaxIterations int     `json:"maxIterations,omitempty"`
+	TargetCV      float64 `json:"targetCV,omitempty"`
+
+	// Trim drops this fraction of the fastest and, separately, the slowest
+	// iterations before the averages are taken (0 keeps them all)
+	Trim float64 `json:"trim"`
+
+	// RecordIterations stores every measured iteration's numbers in the
+	// results alongside the per-pattern aggregates
+	RecordIterations bool `json:"recordIterations"`
+}
+
+// DirList is one or more target directories. In JSON it accepts either a
+// single string or an array of strings.
+type DirList []string
+
+func (d *DirList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*d = DirList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("targetDirectory must be a string or an array of strings")
+	}
+	*d = list
+	return nil
+}
+
+// MarshalJSON keeps single-directory configs in their original string form
+func (d DirList) MarshalJSON() ([]byte, error) {
+	if len(d) == 1 {
+		return json.Marshal(d[0])
+	}
+	return json.Marshal([]string(d))
+}
+
+// PatternSpec is one readPatterns entry. In JSON it is either a bare pattern
+// ID or an object like {"id": 4, "iterations": 50} that overrides the
+// iteration count (maxIterations with auto) for that pattern.
+type PatternSpec struct {
+	ID         int `json:"id"`
+	Iterations int `json:"iterations,omitempty"`
+}
+
+func (p *PatternSpec) UnmarshalJSON(data []byte) error {
+	var id int
+	if err := json.Unmarshal(data, &id); err == nil {
+		*p = PatternSpec{ID: id}
+		return nil
+	}
+	type plain PatternSpec
+	var spec plain
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return fmt.Errorf("readPatterns entries must be pattern IDs or {\"id\", \"iterations\"} objects")
+	}
+	*p = PatternSpec(spec)
+	return nil
+}
+
+// MarshalJSON writes entries without an override as bare IDs
+func (p PatternSpec) MarshalJSON() ([]byte, error) {
+	if p.Iterations == 0 {
+		return json.Marshal(p.ID)
+	}
+	type plain PatternSpec
+	return json.Marshal(plain(p))
+}
+
+func patternSpecs(ids []int) []PatternSpec {
+	specs := make([]PatternSpec, len(ids))
+	for i, id := range ids {
+		specs[i] = PatternSpec{ID: id}
+	}
+	return specs
+}
+
+// hasPattern reports whether patternID is among the ReadPatterns
+func (c BenchmarkConfig) hasPattern(patternID int) bool {
+	return slices.ContainsFunc(c.ReadPatterns, func(p PatternSpec) bool { return p.ID == patternID })
+}
+
+// forPattern returns the config with spec's iteration override applied
+func (c BenchmarkConfig) forPattern(spec PatternSpec) BenchmarkConfig {
+	if spec.Iterations > 0 {
+		c.Iterations = spec.Iterations
+		if c.Auto {
+			c.MaxIterations = spec.Iterations
+		}
+	}
+	return c
+}
+
+// warnStaleConfig warns when a config file was written for a different
+// schema version, listing the fields it leaves unset that were defaulted
+func warnStaleConfig(data []byte, config BenchmarkConfig) {
+	if config.Version == configVersion {
+		return
+	}
+	if config.Version > configVersion {
+		errorf("Warning: config version %d is newer than this binary (version %d), fields it doesn't know are ignored\n",
+			config.Version, configVersion)
+		return
+	}
+
+	// Both unmarshal cleanly: data was already parsed into config
+	var given, effective map[string]json.RawMessage
+	json.Unmarshal(data, &given)
+	encoded, _ := json.Marshal(config)
+	json.Unmarshal(encoded, &effective)
+
+	var defaulted []string
+	for key, value := range effective {
+		if _, ok := given[key]; ok || key == "version" {
+			continue
+		}
+		switch string(value) {
+		case "0", "false", `""`, "null", "[]":
+			continue
+		}
+		defaulted = append(defaulted, key+"="+string(value))
+	}
+	sort.Strings(defaulted)
+
+	if config.Version == 0 {
+		errorf("Warning: config file has no version, this binary expects version %d\n", configVersion)
+	} else {
+		errorf("Warning: config version %d is older than this binary (version %d)\n", config.Version, configVersion)
+	}
+	if len(defaulted) > 0 {
+		errorf("  Defaults applied: %s\n", strings.Join(defaulted, ", "))
+	}
+	errorf("  Check the config and set \"version\": %d to silence this warning\n", configVersion)
+}
+
+// newBenchmarkConfig returns the defaults of fields where 0 is a valid
+// setting, which setDefaults can't tell apart from unset. Config files are
+// decoded over it and the flags default to the same values.
+func newBenchmarkConfig() BenchmarkConfig {
+	return BenchmarkConfig{WriteRatio: 0.5, BimodalSwitch: 0.05, BimodalCold: 0.05, GaussianDrift: 1}
+}
+
+// setDefaults fills in optional fields left unset by older config files
+func (c *BenchmarkConfig) setDefaults() {
+	if c.Concurrency < 1 {
+		c.Concurrency = 1
+	}
+	if c.MaxOpenFiles == 0 {
+		c.MaxOpenFiles = defaultMaxOpenFiles()
+	}
+	if c.Backend == "" {
+		c.Backend = "read"
+	}
+	if c.Backend == "bufio" && c.BufSizeKB == 0 {
+		c.BufSizeKB = 4
+	}
+	if c.MaxRetries > 0 && c.RetryBackoff == "" {
+		c.RetryBackoff = "10ms"
+	}
+	if c.Backend == "iouring" && c.QueueDepth == 0 {
+		c.QueueDepth = 32
+	}
+	if c.Backend == "preadv" && c.IovecKB == 0 {
+		c.IovecKB = 64
+	}
+	if c.HotSetPercent == 0 {
+		c.HotSetPercent = 10
+	}
+	if c.BimodalSetA == [2]float64{} {
+		c.BimodalSetA = [2]float64{0, 10}
+	}
+	if c.BimodalSetB == [2]float64{} {
+		c.BimodalSetB = [2]float64{50, 60}
+	}
+	if c.Auto {
+		if c.MinIterations == 0 {
+			c.MinIterations = 3
+		}
+		if c.MaxIterations == 0 {
+			c.MaxIterations = 50
+		}
+		if c.TargetCV == 0 {
+			c.TargetCV = 0.05
+		}
+	}
+	if c.FileMode == "" {
+		c.FileMode = "0644"
+	}
+	if c.HotSetHitRate == 0 {
+		c.HotSetHitRate = 80
+	}
+	if c.ZipfV == 0 {
+		c.ZipfV = 1
+	}
+	if c.ZipfS == 0 {
+		c.ZipfS = 1.1
+	}
+	if c.RecencyDecay == 0 {
+		c.RecencyDecay = 0.9
+	}
+	if c.Stride == 0 {
+		c.Stride = 4
+	}
+	if c.ReadsPerFile == 0 {
+		c.ReadsPerFile = 1
+	}
+	if c.RepeatMode == "" {
+		c.RepeatMode = "contiguous"
+	}
+	if c.Isolation == "" {
+		c.Isolation = "none"
+	}
+	if c.Runs == 0 {
+		c.Runs = 1
+	}
+	if c.RunIsolation == "" {
+		c.RunIsolation = "none"
+	}
+}
+
+// defaultMaxOpenFiles leaves half of the soft open-file limit for the rest
+// of the process
+func defaultMaxOpenFiles() int {
+	limit, ok := softOpenFileLimit()
+	if !ok || limit < 2 {
+		return 256
+	}
+	if limit > 1<<20 {
+		limit = 1 << 20
+	}
+	return int(limit / 2)
+}
+
+// concurrencyLevels returns the worker counts each pattern runs at
+func (c BenchmarkConfig) concurrencyLevels() []int {
+	if len(c.SweepWorkers) > 0 {
+		return c.SweepWorkers
+	}
+	return []int{c.Concurrency}
+}
+
+// iterationLimit is the most measured iterations a pattern can run
+func (c BenchmarkConfig) iterationLimit() int {
+	if c.Auto {
+		return c.MaxIterations
+	}
+	return c.Iterations
+}
+
+// passes is the warmup plus measured iterations of one pass over every
+// pattern at a single concurrency level
+func (c BenchmarkConfig) passes() int {
+	total := 0
+	for _, spec := range c.ReadPatterns {
+		n := c.Warmup + c.forPattern(spec).iterationLimit()
+		runs := 1
+		if c.withBackgroundWriters(spec.ID) {
+			runs++
+		}
+		if c.comparesAlignment(spec.ID) {
+			runs++
+		}
+		if c.comparesScalar(spec.ID) {
+			runs++
+		}
+		total += n * runs
+	}
+	return total
+}
+
+// withBackgroundWriters reports whether a pattern is measured against
+// background writers, and so also run once without them
+func (c BenchmarkConfig) withBackgroundWriters(patternID int) bool {
+	return c.BackgroundWriters > 0 && !isWritePattern(patternID)
+}
+
+// comparesAlignment reports whether a pattern is also run with misaligned
+// block offsets
+func (c BenchmarkConfig) comparesAlignment(patternID int) bool {
+	return c.CompareAlignment && !isWritePattern(patternID)
+}
+
+// comparesScalar reports whether a pattern read through preadv is first
+// run with scalar block reads of the same size
+func (c BenchmarkConfig) comparesScalar(patternID int) bool {
+	return c.Backend == "preadv" && !isWritePattern(patternID)
+}
+
+// scalarBaseline is c reading the same contiguous iovecKB blocks one ReadAt
+// at a time, front to back like preadv does
+func (c BenchmarkConfig) scalarBaseline() BenchmarkConfig {
+	c.Backend = "read"
+	c.BlockSizeKB = c.IovecKB
+	c.IovecKB = 0
+	c.BlockOffsets = "sequential"
+	c.BlockAlign = 0
+	c.BlockMisalign = false
+	c.ReadFraction = 0
+	return c
+}
+
+// runDuration returns the per-iteration time budget, or 0 for one pass
+// through the access order. Validate has already rejected bad values.
+func (c BenchmarkConfig) runDuration() time.Duration {
+	if c.Duration == "" {
+		return 0
+	}
+	d, _ := time.ParseDuration(c.Duration)
+	return d
+}
+
+// readTimeout returns the per-operation timeout, or 0 for none. Validate has
+// already rejected bad values.
+func (c BenchmarkConfig) readTimeout() time.Duration {
+	if c.ReadTimeout == "" {
+		return 0
+	}
+	d, _ := time.ParseDuration(c.ReadTimeout)
+	return d
+}
+
+// pacing is how an iteration spreads its operations out in time
+type pacing struct {
+	think time.Duration
+	burst int
+	idle  time.Duration
+}
+
+// active reports whether any pauses are taken at all
+func (p pacing) active() bool {
+	return p.think > 0 || p.burst > 0
+}
+
+// pacing returns the configured think time and bursts. Validate has
+// already rejected bad values.
+func (c BenchmarkConfig) pacing() pacing {
+	p := pacing{burst: c.BurstSize}
+	if c.ThinkTime != "" {
+		p.think, _ = time.ParseDuration(c.ThinkTime)
+	}
+	if c.IdleGap != "" {
+		p.idle, _ = time.ParseDuration(c.IdleGap)
+	}
+	return p
+}
+
+// Validate reports the first config field that would make the run
+// meaningless or crash it
+func (c BenchmarkConfig) Validate() error {
+	if c.NumFiles < 1 {
+		return fmt.Errorf("numFiles must be >= 1, got %d", c.NumFiles)
+	}
+	if c.FileSizeKB < 1 {
+		return fmt.Errorf("fileSizeKB must be >= 1, got %d", c.FileSizeKB)
+	}
+	if c.Iterations < 1 {
+		return fmt.Errorf("iterations must be >= 1, got %d", c.Iterations)
+	}
+	if c.Auto {
+		if c.MinIterations < 2 {
+			return fmt.Errorf("minIterations must be >= 2 to measure variation, got %d", c.MinIterations)
+		}
+		if c.MaxIterations < c.MinIterations {
+			return fmt.Errorf("maxIterations (%d) must be >= minIterations (%d)", c.MaxIterations, c.MinIterations)
+		}
+		if c.TargetCV <= 0 {
+			return fmt.Errorf("targetCV must be > 0, got %g", c.TargetCV)
+		}
+	}
+	if len(c.ReadPatterns) == 0 {
+		return fmt.Errorf("readPatterns must not be empty")
+	}
+	for _, p := range c.ReadPatterns {
+		if !isKnownPattern(p.ID) {
+			return fmt.Errorf("readPatterns contains unknown pattern ID %d", p.ID)
+		}
+		if p.Iterations < 0 {
+			return fmt.Errorf("readPatterns iterations for pattern %d must be >= 0, got %d", p.ID, p.Iterations)
+		}
+		if c.Auto && p.Iterations > 0 && p.Iterations < c.MinIterations {
+			return fmt.Errorf("readPatterns iterations for pattern %d (%d) must be >= minIterations (%d)", p.ID, p.Iterations, c.MinIterations)
+		}
+	}
+	if len(c.TargetDirectory) == 0 {
+		return fmt.Errorf("targetDirectory must not be empty")
+	}
+	for _, dir := range c.TargetDirectory {
+		if dir == "" {
+			return fmt.Errorf("targetDirectory must not contain empty paths")
+		}
+	}
+	if c.Concurrency < 0 {
+		return fmt.Errorf("concurrency must be >= 0, got %d", c.Concurrency)
+	}
+	if c.DirDepth < 0 {
+		return fmt.Errorf("dirDepth must be >= 0, got %d", c.DirDepth)
+	}
+	if c.DirDepth > 0 && c.FilesPerDir < 1 {
+		return fmt.Errorf("filesPerDir must be >= 1 with dirDepth, got %d", c.FilesPerDir)
+	}
+	for _, w := range c.SweepWorkers {
+		if w < 1 {
+			return fmt.Errorf("sweepWorkers entries must be >= 1, got %d", w)
+		}
+	}
+	if c.MaxOpenFiles < 1 {
+		return fmt.Errorf("maxOpenFiles must be >= 1, got %d", c.MaxOpenFiles)
+	}
+	if c.BlockSizeKB < 0 {
+		return fmt.Errorf("blockSizeKB must be >= 0, got %d", c.BlockSizeKB)
+	}
+	switch c.Backend {
+	case "", "read":
+	case "mmap", "reuse":
+		if c.BlockSizeKB > 0 {
+			return fmt.Errorf("blockSizeKB is only supported with the read backend")
+		}
+	case "bufio":
+		if c.BlockSizeKB > 0 {
+			return fmt.Errorf("blockSizeKB is only supported with the read backend")
+		}
+		if c.BufSizeKB < 1 {
+			return fmt.Errorf("bufSizeKB must be >= 1, got %d", c.BufSizeKB)
+		}
+	case "gzip":
+		if c.BlockSizeKB > 0 {
+			return fmt.Errorf("blockSizeKB is only supported with the read backend")
+		}
+		for _, p := range c.ReadPatterns {
+			if writesFiles(p.ID) {
+				return fmt.Errorf("the gzip backend doesn't support write patterns")
+			}
+		}
+		if c.Sparse {
+			return fmt.Errorf("sparse files can't be used with the gzip backend, they hold no compressed data")
+		}
+	case "iouring":
+		// One ring takes the place of the workers and has no hook for
+		// per-operation pacing or timeouts
+		if c.BlockSizeKB > 0 {
+			return fmt.Errorf("blockSizeKB is only supported with the read backend")
+		}
+		for _, p := range c.ReadPatterns {
+			if writesFiles(p.ID) {
+				return fmt.Errorf("the iouring backend doesn't support write patterns")
+			}
+		}
+		if c.QueueDepth < 1 || c.QueueDepth > 4096 {
+			return fmt.Errorf("queueDepth must be between 1 and 4096, got %d", c.QueueDepth)
+		}
+		if c.Concurrency > 1 || len(c.SweepWorkers) > 0 {
+			return fmt.Errorf("the iouring backend runs one ring, set queueDepth instead of concurrency")
+		}
+		if c.ReadTimeout != "" || c.MaxRetries > 0 || c.ThinkTime != "" || c.BurstSize > 0 {
+			return fmt.Errorf("the iouring backend doesn't support readTimeout, maxRetries, thinkTime or burstSize")
+		}
+	case "preadv":
+		if c.BlockSizeKB > 0 {
+			return fmt.Errorf("blockSizeKB is only supported with the read backend, set iovecKB instead")
+		}
+		if c.IovecKB < 1 {
+			return fmt.Errorf("iovecKB must be >= 1, got %d", c.IovecKB)
+		}
+	case "quark":
+		// quark has no packed container or reader API to call into, it is
+		// served through the FUSE mount in quark.py
+		return fmt.Errorf("backend quark is not available: mount quark.py and point targetDirectory at its mountpoint with the read backend")
+	default:
+		return fmt.Errorf("backend must be read, mmap, bufio, reuse, gzip, iouring or preadv, got %q", c.Backend)
+	}
+	if c.BlockOffsets != "" && c.BlockOffsets != "sequential" && c.BlockOffsets != "random" {
+		return fmt.Errorf("blockOffsets must be sequential or random, got %q", c.BlockOffsets)
+	}
+	if c.BlockAlign < 0 || c.BlockAlign&(c.BlockAlign-1) != 0 {
+		return fmt.Errorf("blockAlign must be a power of two, got %d", c.BlockAlign)
+	}
+	if c.BlockAlign > 0 && (c.BlockSizeKB == 0 || c.BlockSizeKB*1024%c.BlockAlign != 0) {
+		return fmt.Errorf("blockAlign needs block reads with blockSizeKB a multiple of it")
+	}
+	if (c.BlockMisalign || c.CompareAlignment) && c.BlockAlign < 2 {
+		return fmt.Errorf("blockMisalign and compareAlignment need a blockAlign of at least 2")
+	}
+	if (c.BlockMisalign || c.CompareAlignment) && c.Verify {
+		return fmt.Errorf("misaligned block reads skip the start of each file and can't be verified")
+	}
+	if c.Trim < 0 || c.Trim >= 0.5 {
+		return fmt.Errorf("trim must be in [0, 0.5), got %g", c.Trim)
+	}
+	if c.MissingRatio < 0 || c.MissingRatio >= 1 {
+		return fmt.Errorf("missingRatio must be in [0, 1), got %g", c.MissingRatio)
+	}
+	if c.WriteRatio < 0 || c.WriteRatio > 1 {
+		return fmt.Errorf("writeRatio must be between 0 and 1, got %g", c.WriteRatio)
+	}
+	if c.Verify {
+		for _, p := range c.ReadPatterns {
+			if p.ID == PatternMixed {
+				return fmt.Errorf("verify can't be used with the Mixed pattern, its reads race its overwrites")
+			}
+		}
+	}
+	if c.BackgroundWriters < 0 {
+		return fmt.Errorf("backgroundWriters must be >= 0, got %d", c.BackgroundWriters)
+	}
+	if c.BackgroundWriters > 0 && (c.Verify || c.Backend == "gzip") {
+		return fmt.Errorf("backgroundWriters overwrite files with random data, which verify and the gzip backend can't read back")
+	}
+	if c.hasPattern(PatternTraceReplay) && c.TraceFile == "" {
+		return fmt.Errorf("the Trace Replay pattern needs a traceFile")
+	}
+	if _, err := parseFileMode(c.FileMode); err != nil {
+		return err
+	}
+	if c.Compressibility < 0 || c.Compressibility > 1 {
+		return fmt.Errorf("compressibility must be between 0 and 1, got %g", c.Compressibility)
+	}
+	if c.ZeroContent && (c.Compressibility > 0 || c.Backend == "gzip") {
+		return fmt.Errorf("zeroContent data is fully compressible, it can't be combined with compressibility or the gzip backend")
+	}
+	if c.Verify && c.BlockSizeKB > 0 && c.BlockOffsets == "random" {
+		return fmt.Errorf("verify needs whole files or sequential block offsets")
+	}
+	if c.FadviseSequential && c.Backend != "" && c.Backend != "read" {
+		return fmt.Errorf("fadviseSequential is only supported with the read backend")
+	}
+	if c.ReadFraction < 0 || c.ReadFraction > 1 {
+		return fmt.Errorf("readFraction must be between 0 and 1, got %g", c.ReadFraction)
+	}
+	if c.ReadFraction > 0 && (c.Backend != "read" || c.BlockSizeKB > 0 || c.FadviseSequential || c.PhaseTiming) {
+		return fmt.Errorf("readFraction needs plain reads: the read backend without blockSizeKB, fadviseSequential or phaseTiming")
+	}
+	if c.ReadFraction > 0 && c.Verify {
+		return fmt.Errorf("verify checks whole files and can't be combined with readFraction")
+	}
+	if c.PhaseTiming && (c.Backend != "read" || c.BlockSizeKB > 0 || c.FadviseSequential) {
+		return fmt.Errorf("phaseTiming needs plain whole-file reads: the read backend without blockSizeKB or fadviseSequential")
+	}
+	if c.Warmup < 0 {
+		return fmt.Errorf("warmup must be >= 0, got %d", c.Warmup)
+	}
+	switch c.SizeDistribution {
+	case "", "fixed":
+	case "uniform":
+		if c.MinFileSizeKB < 1 || c.MaxFileSizeKB < c.MinFileSizeKB {
+			return fmt.Errorf("uniform sizes need 1 <= minFileSizeKB <= maxFileSizeKB, got %d..%d", c.MinFileSizeKB, c.MaxFileSizeKB)
+		}
+	case "lognormal":
+		if c.SizeSigma <= 0 {
+			return fmt.Errorf("lognormal sizes need sizeSigma > 0, got %g", c.SizeSigma)
+		}
+	default:
+		return fmt.Errorf("sizeDistribution must be fixed, uniform or lognormal, got %q", c.SizeDistribution)
+	}
+	if c.HotSetPercent <= 0 || c.HotSetPercent > 100 {
+		return fmt.Errorf("hotSetPercent must be in (0,100], got %g", c.HotSetPercent)
+	}
+	if c.HotSetHitRate <= 0 || c.HotSetHitRate > 100 {
+		return fmt.Errorf("hotSetHitRate must be in (0,100], got %g", c.HotSetHitRate)
+	}
+	for _, set := range [][2]float64{c.BimodalSetA, c.BimodalSetB} {
+		if set[0] < 0 || set[0] >= set[1] || set[1] > 100 {
+			return fmt.Errorf("bimodal sets must be [start, end) percent ranges within [0,100], got %v", set)
+		}
+	}
+	if c.BimodalSwitch < 0 || c.BimodalSwitch > 1 {
+		return fmt.Errorf("bimodalSwitch must be between 0 and 1, got %g", c.BimodalSwitch)
+	}
+	if c.BimodalCold < 0 || c.BimodalCold > 1 {
+		return fmt.Errorf("bimodalCold must be between 0 and 1, got %g", c.BimodalCold)
+	}
+	if c.ZipfS <= 1 {
+		return fmt.Errorf("zipfS must be > 1, got %g", c.ZipfS)
+	}
+	if c.ZipfV < 1 {
+		return fmt.Errorf("zipfV must be >= 1, got %g", c.ZipfV)
+	}
+	if c.RecencyDecay <= 0 || c.RecencyDecay >= 1 {
+		return fmt.Errorf("recencyDecay must be between 0 and 1 exclusive, got %g", c.RecencyDecay)
+	}
+	if c.Stride < 1 {
+		return fmt.Errorf("stride must be >= 1, got %d", c.Stride)
+	}
+	if c.ReadsPerFile < 1 {
+		return fmt.Errorf("readsPerFile must be >= 1, got %d", c.ReadsPerFile)
+	}
+	if c.SampleSize < 0 {
+		return fmt.Errorf("sampleSize must be >= 0, got %d", c.SampleSize)
+	}
+	if c.RepeatMode != "contiguous" && c.RepeatMode != "interleaved" {
+		return fmt.Errorf("repeatMode must be contiguous or interleaved, got %q", c.RepeatMode)
+	}
+	if c.Duration != "" {
+		d, err := time.ParseDuration(c.Duration)
+		if err != nil {
+			return fmt.Errorf("duration: %v", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("duration must be positive, got %s", c.Duration)
+		}
+	}
+	if c.ThinkTime != "" {
+		d, err := time.ParseDuration(c.ThinkTime)
+		if err != nil {
+			return fmt.Errorf("thinkTime: %v", err)
+		}
+		if d < 0 {
+			return fmt.Errorf("thinkTime must not be negative, got %s", c.ThinkTime)
+		}
+	}
+	if c.BurstSize < 0 {
+		return fmt.Errorf("burstSize must be >= 0, got %d", c.BurstSize)
+	}
+	if c.IdleGap != "" {
+		d, err := time.ParseDuration(c.IdleGap)
+		if err != nil {
+			return fmt.Errorf("idleGap: %v", err)
+		}
+		if d < 0 {
+			return fmt.Errorf("idleGap must not be negative, got %s", c.IdleGap)
+		}
+		if c.BurstSize == 0 {
+			return fmt.Errorf("idleGap needs a burstSize to separate")
+		}
+	}
+	if c.ReadTimeout != "" {
+		d, err := time.ParseDuration(c.ReadTimeout)
+		if err != nil {
+			return fmt.Errorf("readTimeout: %v", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("readTimeout must be positive, got %s", c.ReadTimeout)
+		}
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("maxRetries must be >= 0, got %d", c.MaxRetries)
+	}
+	if c.RetryBackoff != "" {
+		d, err := time.ParseDuration(c.RetryBackoff)
+		if err != nil {
+			return fmt.Errorf("retryBackoff: %v", err)
+		}
+		if d < 0 {
+			return fmt.Errorf("retryBackoff must not be negative, got %s", c.RetryBackoff)
+		}
+	}
+	if c.Isolation != "none" && c.Isolation != "dropcache" && c.Isolation != "regenerate" {
+		return fmt.Errorf("isolation must be none, dropcache or regenerate, got %q", c.Isolation)
+	}
+	if c.Runs < 1 {
+		return fmt.Errorf("runs must be >= 1, got %d", c.Runs)
+	}
+	if c.RunIsolation != "none" && c.RunIsolation != "dropcache" && c.RunIsolation != "regenerate" {
+		return fmt.Errorf("runIsolation must be none, dropcache or regenerate, got %q", c.RunIsolation)
+	}
+	if c.CacheSizeFiles < 0 {
+		return fmt.Errorf("cacheSizeFiles must be >= 0, got %d", c.CacheSizeFiles)
+	}
+	if c.ThroughputWindowMs < 0 {
+		return fmt.Errorf("throughputWindowMs must be >= 0, got %d", c.ThroughputWindowMs)
+	}
+	if c.GaussianStdDev < 0 {
+		return fmt.Errorf("gaussianStdDev must be >= 0, got %g", c.GaussianStdDev)
+	}
+	if math.IsNaN(c.GaussianDrift) || math.IsInf(c.GaussianDrift, 0) {
+		return fmt.Errorf("gaussianDrift must be a finite number, got %g", c.GaussianDrift)
+	}
+	return nil
 }
 
 type BenchmarkResult struct {
 	Pattern      string        `json:"pattern"`
+	Directory    string        `json:"directory"`
+	RunOrder     int           `json:"runOrder"`
+	Run          int           `json:"run,omitempty"`
 	Duration     time.Duration `json:"duration"`
 	FileCount    int           `json:"fileCount"`
 	BytesRead    int64         `json:"bytesRead"`
 	ReadPerSec   float64       `json:"reads_per_sec"`
+	IOPS         float64       `json:"iops"`
+	AvgReadBytes int64         `json:"avgReadBytes"`
 	MBytesPerSec float64       `json:"mbytes_per_sec"`
-}
+	P50          time.Duration `json:"p50"`
+	P95          time.Duration `json:"p95"`
+	P99          time.Duration `json:"p99"`
+	MaxLatency   time.Duration `json:"maxLatency"`
+	Concurrency  int           `json:"concurrency"`
+	Backend      string        `json:"backend"`
+	BlockSizeKB  int           `json:"blockSizeKB,omitempty"`
+	BlockAlign   int           `json:"blockAlign,omitempty"`
+	Misaligned   bool          `json:"misaligned,omitempty"`
+	BufSizeKB    int           `json:"bufSizeKB,omitempty"`
+	QueueDepth   int           `json:"queueDepth,omitempty"`
+	IovecKB      int           `json:"iovecKB,omitempty"`
+	TraceFile    string        `json:"traceFile,omitempty"`
+	Iterations   int           `json:"iterations"`
+	WallDuration time.Duration `json:"wallDuration,omitempty"`
+	MinDuration  time.Duration `json:"minDuration"`
+	MaxDuration  time.Duration `json:"maxDuration"`
+	StdDevMs     float64       `json:"stddev_ms"`
+	Error        string        `json:"error,omitempty"`
 
-type BenchmarkResults struct {
+	// TrimmedIterations were successful but left out of the averages
+	TrimmedIterations int `json:"trimmedIterations,omitempty"`
+
+	// PerIteration holds each successful iteration in run order when
+	// RecordIterations is set
+	PerIteration []IterationSample `json:"perIteration,omitempty"`
+
+	// Read and write throughput of the Mixed pattern, each over the time
+	// spent in operations of that kind
+	ReadMBytesPerSec  float64 `json:"read_mbytes_per_sec,omitempty"`
+	WriteMBytesPerSec float64 `json:"write_mbytes_per_sec,omitempty"`
+
+	// MissingReads counts reads that found their file missing and
+	// TimedOutReads those abandoned after ReadTimeout, summed over the
+	// successful iterations
+	MissingReads  int64 `json:"missingReads,omitempty"`
+	TimedOutReads int64 `json:"timedOutReads,omitempty"`
+
+	// Retries counts retried operations and RetryTime what the failed
+	// attempts and backoffs took, summed over the successful iterations.
+	// RetryTime is left out of Duration but retried operations' latencies
+	// still include it.
+	Retries   int64         `json:"retries,omitempty"`
+	RetryTime time.Duration `json:"retryTime,omitempty"`
+
+	// With BackgroundWriters, the overwrites they made while the pattern
+	// was measured, the pattern's throughput without them and the share of
+	// it they cost
+	BackgroundWriters    int     `json:"backgroundWriters,omitempty"`
+	BackgroundWrites     int64   `json:"backgroundWrites,omitempty"`
+	BackgroundWriteBytes int64   `json:"backgroundWriteBytes,omitempty"`
+	BaselineMBytesPerSec float64 `json:"baselineMBytesPerSec,omitempty"`
+	WriterSlowdown       float64 `json:"writerSlowdownPercent,omitempty"`
+
+	// With CompareAlignment, the pattern's throughput with misaligned
+	// block offsets and the share of the aligned throughput it loses
+	MisalignedMBytesPerSec float64 `json:"misalignedMBytesPerSec,omitempty"`
+	MisalignSlowdown       float64 `json:"misalignSlowdownPercent,omitempty"`
+
+	// With the preadv backend, the pattern's throughput reading the same
+	// iovecKB blocks one ReadAt at a time and how much faster preadv was
+	ScalarMBytesPerSec float64 `json:"scalarMBytesPerSec,omitempty"`
+	VectoredSpeedup    float64 `json:"vectoredSpeedupPercent,omitempty"`
+
+	// HitRatio is the fraction of reads the simulated LRU cache would have
+	// served, set only when CacheSizeFiles is configured
+	HitRatio *float64 `json:"hitRatio,omitempty"`
+
+	// UniqueFiles is the number of distinct files the measured iterations
+	// accessed and CoveragePercent the share of the dataset that is.
+	// NeverAccessed lists the indices of the rest as ranges, e.g. "3,7-12".
+	UniqueFiles     int     `json:"uniqueFiles"`
+	CoveragePercent float64 `json:"coveragePercent"`
+	NeverAccessed   string  `json:"neverAccessed,omitempty"`
+
+	// Mean time per whole-file read spent in each phase, set only with
+	// PhaseTiming
+	OpenLatency  time.Duration `json:"openLatency,omitempty"`
+	StatLatency  time.Duration `json:"statLatency,omitempty"`
+	ReadLatency  time.Duration `json:"readLatency,omitempty"`
+	CloseLatency time.Duration `json:"closeLatency,omitempty"`
+
+	// Syscalls the measured iterations' operations made by kind and their
+	// sum per operation, set only with CountSyscalls
+	Syscalls      *SyscallCounts `json:"syscalls,omitempty"`
+	SyscallsPerOp float64        `json:"syscallsPerOp,omitempty"`
+
+	// ReadFraction is the leading share of each file read, if not all of it
+	ReadFraction float64 `json:"readFraction,omitempty"`
+
+	// Streaming marks percentiles taken from a histogram rather than every
+	// sample
+	Streaming bool `json:"streaming,omitempty"`
+
+	// Tmpfs records that the files were held in RAM
+	Tmpfs bool `json:"tmpfs,omitempty"`
+
+	// FadviseSequential records that this pattern's reads were issued
+	// with the POSIX_FADV_SEQUENTIAL hint
+	FadviseSequential bool `json:"fadviseSequential,omitempty"`
+
+	// WorkerLoads sums each concurrent worker's reads and bytes over the
+	// successful iterations, Fairness is Jain's index of their bytes
+	WorkerLoads []WorkerLoad `json:"workerLoads,omitempty"`
+	Fairness    float64      `json:"fairness,omitempty"`
+
+	// Go runtime cost of the measured iterations: bytes allocated, heap
+	// allocations, GC cycles and total stop-the-world GC pause
+	AllocBytes  uint64        `json:"allocBytes"`
+	Mallocs     uint64        `json:"mallocs"`
+	NumGC       uint32        `json:"numGC"`
+	GCPauseTime time.Duration `json:"gcPauseTime"`
+
+	Throughput *ThroughputSeries `json:"throughput,omitempty"`
+}
+
+// IterationSample is one measured iteration as stored in the results
+type IterationSample struct {
+	Duration  time.Duration `json:"duration"`
+	BytesRead int64         `json:"bytesRead"`
+	Ops       int64         `json:"ops"`
+	Trimmed   bool          `json:"trimmed,omitempty"`
+}
+
+// ThroughputSeries holds, for every measured iteration, the bytes completed
+// in each consecutive WindowMs window
+type ThroughputSeries struct {
+	WindowMs int       `json:"windowMs"`
+	Bytes    [][]int64 `json:"bytes"`
+}
+
+type DatasetStats struct {
+	TotalBytes  int64 `json:"totalBytes"`
+	MinFileSize int64 `json:"minFileSize"`
+	MaxFileSize int64 `json:"maxFileSize"`
+	AvgFileSize int64 `json:"avgFileSize"`
+}
+
+type BenchmarkResults struct {
 	Config  BenchmarkConfig   `json:"config"`
+	Dataset DatasetStats      `json:"dataset"`
 	Results []BenchmarkResult `json:"results"`
-	System  struct {
+	Partial bool              `json:"partial,omitempty"`
+
+	// Aggregates combines each pattern's results across Runs, only set
+	// when there was more than one run
+	Aggregates []RunAggregate `json:"aggregates,omitempty"`
+
+	// InProgress describes the pattern currently running, it is only set
+	// while serving live results
+	InProgress *PatternProgress `json:"inProgress,omitempty"`
+
+	// Wall-clock time of the whole suite and the number of reads issued
+	// by measured iterations across every pattern
+	TotalDuration time.Duration `json:"totalDuration"`
+	TotalReads    int64         `json:"totalReads"`
+	System        struct {
 		Timestamp string `json:"timestamp"`
 		Hostname  string `json:"hostname"`
+		Seed      int64  `json:"seed"`
+		CacheNote string `json:"cacheNote"`
+		OS        string `json:"os"`
+		Arch      string `json:"arch"`
+		NumCPU    int    `json:"numCPU"`
+		CPUModel  string `json:"cpuModel,omitempty"`
+		TotalRAM  uint64 `json:"totalRAM,omitempty"`
+		GoVersion string `json:"goVersion"`
+
+		// Filesystems maps each target directory to the type of
+		// filesystem it was on, a raw statfs magic number if unknown
+		Filesystems map[string]string `json:"filesystems,omitempty"`
 	} `json:"system"`
 }
 
-type FileInfo struct {
-	Path     string
-	Size     int64
-	Contents []byte
+// RunAggregate is the mean and sample standard deviation of one pattern's
+// throughput over the runs in which it succeeded
+type RunAggregate struct {
+	Pattern            string  `json:"pattern"`
+	Directory          string  `json:"directory"`
+	Concurrency        int     `json:"concurrency"`
+	Runs               int     `json:"runs"`
+	MBytesPerSec       float64 `json:"mbytes_per_sec"`
+	MBytesPerSecStdDev float64 `json:"mbytes_per_sec_stddev"`
+	ReadPerSec         float64 `json:"reads_per_sec"`
+	ReadPerSecStdDev   float64 `json:"reads_per_sec_stddev"`
+}
+
+type PatternProgress struct {
+	Directory  string `json:"directory"`
+	Pattern    string `json:"pattern"`
+	Iteration  int    `json:"iteration"`
+	Iterations int    `json:"iterations"`
+}
+
+type FileInfo struct {
+	Path string
+	Size int64
+
+	// Checksum is the CRC32 (IEEE) of the file's contents
+	Checksum uint32
+}
+
+const (
+	PatternSequential      = 1
+	PatternReverseSeq      = 2
+	PatternRandom          = 3
+	PatternZipfian         = 4
+	PatternLocalityBased   = 5
+	PatternRepeatedAccess  = 6
+	PatternWriteSequential = 7
+	PatternWriteRandom     = 8
+	PatternGaussian        = 9
+	PatternStrided         = 10
+	PatternMixed           = 11
+	PatternRecencyDecay    = 12
+	PatternTraceReplay     = 13
+	PatternBimodal         = 14
+	PatternSizeAscending   = 15
+	PatternSizeDescending  = 16
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		runCompareCommand(os.Args[2:])
+		return
+	}
+
+	configPath := flag.String("config", "", "Path to configuration JSON file (gunzipped if it ends in .gz)")
+	outputPath := flag.String("output", "benchmark_results.json", "Path to output JSON results (gzipped if it ends in .gz)")
+	csvPath := flag.String("csv", "", "Also write results as CSV to this path")
+	chart := flag.Bool("chart", false, "Print a bar chart of MB/s per pattern after the summary")
+	historyPath := flag.String("history", "", "Append the results to the JSON array of past runs in this file")
+	historyKeep := flag.Int("history-keep", 0, "With -history, keep only this many most recent runs (0 = all)")
+	trend := flag.Bool("trend", false, "With -history, print each pattern's MB/s across the kept runs")
+	influxPath := flag.String("influx", "", "Also write results as InfluxDB line protocol to this path")
+	influxURL := flag.String("influx-url", "", "POST results as InfluxDB line protocol to this write URL (token from $INFLUX_TOKEN)")
+	sampleSize := flag.Int("sample", 0, "Operations per iteration drawn from the pattern over all files (0 = one full pass)")
+	missingRatio := flag.Float64("missing", 0, "Fraction of files to delete after setup, to exercise missing-file reads")
+	auto := flag.Bool("auto", false, "Iterate each pattern until its durations stabilize instead of a fixed -iter")
+	minIter := flag.Int("min-iter", 3, "With -auto, minimum iterations and the window the CV is taken over")
+	maxIter := flag.Int("max-iter", 50, "With -auto, maximum iterations per pattern")
+	perIter := flag.Bool("per-iter", false, "Store every iteration's duration and bytes in the JSON results")
+	trim := flag.Float64("trim", 0, "Fraction of fastest and of slowest iterations left out of the averages, e.g. 0.1")
+	targetCV := flag.Float64("target-cv", 0.05, "With -auto, stop once the coefficient of variation drops below this")
+	fsync := flag.Bool("fsync", false, "Write benchmarks sync each file after writing it")
+	countSyscalls := flag.Bool("syscalls", false, "Count the syscalls each operation makes by kind (open, read, fstat, mmap, io_uring_enter...)")
+	streaming := flag.Bool("stream", false, "Keep latencies in a fixed-size histogram and expand -repeat passes lazily (the base access order is still built in full)")
+	phases := flag.Bool("phases", false, "Time open, stat, read and close of each file separately")
+	fadviseSeq := flag.Bool("fadvise-seq", false, "Advise the kernel the Sequential and Strided patterns read files sequentially (Linux only)")
+	oDirect := flag.Bool("odirect", false, "Write benchmarks open files with O_DIRECT (Linux only)")
+	dirDepth := flag.Int("dir-depth", 0, "Spread files over a directory tree this many levels deep (0 = flat)")
+	filesPerDir := flag.Int("files-per-dir", 100, "With -dir-depth, entries per directory in the tree")
+	sweepWorkers := flag.String("sweep-workers", "", "Run every pattern at each of these comma-separated worker counts, e.g. 1,2,4,8")
+	jsonlPath := flag.String("jsonl", "", "Append each result to this JSON Lines file as soon as its pattern finishes")
+	mdPath := flag.String("md", "", "Also write a Markdown report to this path")
+	comparePath := flag.String("compare", "", "Compare this run against a baseline results JSON file")
+	threshold := flag.Float64("fail-threshold", 5, "With -compare, exit with status 2 if any pattern's MB/s drops by more than this percentage")
+	flag.Float64Var(threshold, "threshold", 5, "Alias of -fail-threshold")
+	keep := flag.Bool("keep", false, "Keep the generated files instead of cleaning up")
+	reuse := flag.Bool("reuse", false, "Reuse matching test files already in the target directory and keep them afterwards")
+	regenerate := flag.Bool("regenerate", false, "With -reuse, recreate the files if the existing ones don't match")
+	dumpOrder := flag.String("dump-order", "", "Write every pattern's access orders and file paths as JSON files in this directory")
+	dumpLimit := flag.Int("dump-limit", 100000, "With -dump-order, write at most this many accesses per pattern (0 = no limit)")
+	manifestPath := flag.String("manifest", "", "Write the paths, sizes and CRC32s of the generated files to this JSON file, with -reuse an existing one supplies the checksums")
+	pushGatewayURL := flag.String("prometheus-pushgateway", "", "Push result gauges to this Prometheus push gateway URL")
+	httpAddr := flag.String("http", "", "Serve live results on this address, e.g. :8080")
+	verbose := flag.Bool("v", false, "Verbose output, including the timing of every read")
+	quiet := flag.Bool("quiet", false, "Only print errors, warnings and the final summary")
+	force := flag.Bool("force", false, "Generate the dataset even if it would fill most of the free disk space")
+	dryRun := flag.Bool("dryrun", false, "Print the planned workload and exit without creating or reading files")
+	cpuProfile := flag.String("cpuprofile", "", "Write a CPU profile of each measured iteration, named after this file with .<pattern>.<n> before the extension")
+	memProfile := flag.String("memprofile", "", "Write a heap profile after the measured iterations to this file")
+	traceOut := flag.String("trace", "", "Write a runtime execution trace of each measured iteration, named like -cpuprofile's (slows reads down)")
+	profilePattern := flag.String("profile-pattern", "", "Limit -cpuprofile and -trace to the measured iterations of this one pattern")
+	numFiles := flag.Int("files", 100, "Number of files to create")
+	fileSizeKB := flag.Int("size", 1024, "Size of each file in KB")
+	targetDir := flag.String("dir", "benchmark_files", "Directory to create files in (comma-separated to compare several)")
+	iterations := flag.Int("iter", 10, "Number of iterations for each benchmark")
+	seed := flag.Int64("seed", 0, "Random seed for access patterns (0 = time-based)")
+	workers := flag.Int("workers", 1, "Number of concurrent readers")
+	maxOpenFiles := flag.Int("max-open-files", 0, "Maximum files open at once by the readers, timed out reads still running included (0 = half the soft rlimit)")
+	cold := flag.Bool("cold", false, "Drop the OS page cache before each iteration")
+	mode := flag.String("mode", "read", "Default pattern set to run: read, write, both, or mixed")
+	listPatterns := flag.Bool("list-patterns", false, "Print every pattern's ID, name and description, then exit")
+	patternNames := flag.String("patterns", "", "Comma-separated pattern names to run instead, e.g. sequential,zipfian (overrides -mode and the config)")
+	writeNew := flag.Bool("write-new", false, "Write benchmarks create new files instead of overwriting")
+	blockSizeKB := flag.Int("block", 0, "Read files in blocks of this many KB via ReadAt (0 = whole-file reads)")
+	readFraction := flag.Float64("read-fraction", 0, "Read only this leading fraction of each file, e.g. 0.1 (0 = whole files)")
+	blockOffsets := flag.String("block-offsets", "sequential", "Block offsets within each file: sequential or random")
+	blockAlign := flag.Int("block-align", 0, "Align block read offsets to this many bytes, e.g. 512 or 4096")
+	blockMisalign := flag.Bool("block-misalign", false, "Shift every block read offset half of -block-align off the boundary")
+	compareAlignment := flag.Bool("compare-alignment", false, "Measure block read patterns aligned to -block-align and again misaligned")
+	sizeDist := flag.String("size-dist", "fixed", "File size distribution: fixed, uniform, or lognormal")
+	minSizeKB := flag.Int("min-size", 0, "Minimum file size in KB for uniform sizes")
+	maxSizeKB := flag.Int("max-size", 0, "Maximum file size in KB for uniform sizes")
+	sizeSigma := flag.Float64("size-sigma", 1.0, "Sigma of the underlying normal for lognormal sizes")
+	backend := flag.String("backend", "read", "Read backend: read, mmap, bufio, reuse (one preallocated buffer per worker), gzip (files stored compressed, decompressed on read), iouring or preadv (vectored block reads, compared against scalar ones) (both Linux only)")
+	bufSizeKB := flag.Int("bufsize", 0, "Buffer size in KB for the bufio backend (0 = 4 KB)")
+	queueDepth := flag.Int("queue-depth", 0, "Reads kept in flight by the iouring backend (0 = 32)")
+	iovecKB := flag.Int("iovec", 0, "Size in KB of each iovec the preadv backend reads into (0 = 64 KB)")
+	hotSetPercent := flag.Float64("hotset", 10, "Percentage of files in the Repeated Access hot set")
+	hotSetHitRate := flag.Float64("hotset-hit", 80, "Percentage of Repeated Access reads that go to the hot set")
+	traceFile := flag.String("trace-file", "", "Replay the file indices or names listed one per line in this file as the Trace Replay pattern")
+	stride := flag.Int("stride", 4, "Distance between consecutive files in the Strided pattern")
+	repeat := flag.Int("repeat", 1, "Number of times each file in the access order is read per iteration")
+	repeatMode := flag.String("repeat-mode", "contiguous", "How repeated reads are ordered: contiguous (aabb) or interleaved (abab)")
+	isolate := flag.String("isolate", "none", "Reset cache state between patterns: none, dropcache, or regenerate")
+	runCount := flag.Int("runs", 1, "Run the whole suite this many times and aggregate each pattern across runs")
+	runIsolate := flag.String("runs-isolate", "none", "Reset cache state between runs: none, dropcache, or regenerate")
+	shufflePatterns := flag.Bool("shuffle-patterns", false, "Run patterns in a random order in each suite")
+	burstSize := flag.Int("burst", 0, "Issue operations in bursts of this many separated by -idle-gap (0 = no bursts)")
+	idleGap := flag.String("idle-gap", "", "Idle time after each burst, e.g. 50ms (excluded from the timings)")
+	maxRetries := flag.Int("retries", 0, "Retry a failed operation this many times before counting it as an error")
+	retryBackoff := flag.String("retry-backoff", "", "Wait this long before the first retry, doubling for each one after (default 10ms)")
+	readTimeout := flag.String("read-timeout", "", "Count an operation taking longer than this as timed out and move on, e.g. 5s (adds a goroutine per operation)")
+	thinkTime := flag.String("think", "", "Pause each reader this long between operations, e.g. 1ms (excluded from the timings)")
+	runDur := flag.String("rundur", "", "Run each iteration for this long, e.g. 10s, looping the access order (default one pass)")
+	writeRatio := flag.Float64("write-ratio", 0.5, "Fraction of Mixed pattern operations that are overwrites")
+	bimodalSwitch := flag.Float64("bimodal-switch", 0.05, "Chance per access that the Bimodal pattern switches hot sets")
+	bimodalCold := flag.Float64("bimodal-cold", 0.05, "Fraction of Bimodal pattern accesses that go to a random file")
+	backgroundWriters := flag.Int("background-writers", 0, "Goroutines overwriting random files while read patterns are measured, compared against a run without them")
+	tmpfs := flag.Bool("tmpfs", false, "Hold the files in RAM, mounting a tmpfs on each target directory unless it already is one (Linux, mounting needs root)")
+	sparse := flag.Bool("sparse", false, "Create files as holes of the target size instead of writing data (reads return zeros)")
+	chmod := flag.String("chmod", "0644", "Octal permissions for generated files, e.g. 0600 or 4755")
+	zeroContent := flag.Bool("zero-content", false, "Fill files with a cheap repeating pattern instead of random data (fast setup, fully compressible)")
+	compressibility := flag.Float64("compressibility", 0, "Fraction of generated content that is compressible, 0 (random) to 1")
+	verify := flag.Bool("verify", false, "Check every read against the checksum recorded when the file was written")
+	cacheSize := flag.Int("cache-size", 0, "Simulate an LRU cache of this many files and report its hit ratio (0 = off)")
+	window := flag.Int("window", 0, "Record throughput over time in windows of this many ms (0 = off)")
+	zipfS := flag.Float64("zipf-s", 1.1, "Zipfian skew parameter s (must be > 1)")
+	zipfV := flag.Float64("zipf-v", 1, "Zipfian offset v (must be >= 1), larger values flatten the head of the distribution")
+	zipfPermute := flag.Bool("zipf-permute", false, "Map Zipfian ranks to a seeded random permutation of the files instead of index order")
+	recencyDecay := flag.Float64("recency-decay", 0.9, "Weight decay per recency rank in the Recency Decay pattern (0 < d < 1)")
+	warmup := flag.Int("warmup", 0, "Number of unmeasured warmup iterations per pattern")
+	gaussStdDev := flag.Float64("gauss-stddev", 0, "Standard deviation in files for the Gaussian pattern (0 = files/6)")
+	gaussDrift := flag.Float64("gauss-drift", 1, "Files the Gaussian pattern's center moves per access, wrapping around (0 = fixed at the middle file)")
+	flag.Parse()
+
+	if *listPatterns {
+		printPatterns()
+		return
+	}
+
+	switch {
+	case *verbose && *quiet:
+		errorf("Error: -v and -quiet can't be combined\n")
+		os.Exit(1)
+	case *verbose:
+		currentLevel = levelVerbose
+	case *quiet:
+		currentLevel = levelQuiet
+	}
+
+	config := newBenchmarkConfig()
+	var configData []byte
+
+	if *configPath != "" {
+		data, err := readFileGz(*configPath)
+		if err != nil {
+			errorf("Error reading config file: %v\n", err)
+			os.Exit(1)
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			errorf("Error parsing config file: %v\n", err)
+			os.Exit(1)
+		}
+		configData = data
+	} else {
+		readPatterns := []int{PatternSequential, PatternReverseSeq, PatternRandom, PatternZipfian, PatternLocalityBased, PatternRepeatedAccess, PatternGaussian, PatternRecencyDecay}
+		writePatterns := []int{PatternWriteSequential, PatternWriteRandom}
+
+		var patterns []int
+		switch *mode {
+		case "read":
+			patterns = readPatterns
+		case "write":
+			patterns = writePatterns
+		case "both":
+			patterns = append(readPatterns, writePatterns...)
+		case "mixed":
+			patterns = []int{PatternMixed}
+		default:
+			errorf("Unknown mode %q: expected read, write, both, or mixed\n", *mode)
+			os.Exit(1)
+		}
+		if *traceFile != "" {
+			patterns = append(patterns, PatternTraceReplay)
+		}
+
+		config = BenchmarkConfig{
+			Version:         configVersion,
+			NumFiles:        *numFiles,
+			FileSizeKB:      *fileSizeKB,
+			ReadPatterns:    patternSpecs(patterns),
+			TargetDirectory: strings.Split(*targetDir, ","),
+			Iterations:      *iterations,
+			Seed:            *seed,
+			Concurrency:     *workers,
+			MaxOpenFiles:    *maxOpenFiles,
+			DropCache:       *cold,
+			WriteNewFiles:   *writeNew,
+			BlockSizeKB:     *blockSizeKB,
+			BlockOffsets:    *blockOffsets,
+			GaussianStdDev:  *gaussStdDev,
+			GaussianDrift:   *gaussDrift,
+			Warmup:          *warmup,
+			Backend:         *backend,
+			BufSizeKB:       *bufSizeKB,
+			QueueDepth:      *queueDepth,
+			IovecKB:         *iovecKB,
+			HotSetPercent:   *hotSetPercent,
+			HotSetHitRate:   *hotSetHitRate,
+			ZipfS:           *zipfS,
+			ZipfV:           *zipfV,
+			ZipfPermute:     *zipfPermute,
+			RecencyDecay:    *recencyDecay,
+			TraceFile:       *traceFile,
+			Stride:          *stride,
+			ReadsPerFile:    *repeat,
+			RepeatMode:      *repeatMode,
+			SampleSize:      *sampleSize,
+
+			ThroughputWindowMs: *window,
+			Duration:           *runDur,
+			ThinkTime:          *thinkTime,
+			BurstSize:          *burstSize,
+			IdleGap:            *idleGap,
+			ReadTimeout:        *readTimeout,
+			MaxRetries:         *maxRetries,
+			RetryBackoff:       *retryBackoff,
+			Isolation:          *isolate,
+			Runs:               *runCount,
+			RunIsolation:       *runIsolate,
+			ShufflePatterns:    *shufflePatterns,
+
+			SizeDistribution: *sizeDist,
+			MinFileSizeKB:    *minSizeKB,
+			MaxFileSizeKB:    *maxSizeKB,
+			SizeSigma:        *sizeSigma,
+
+			CacheSizeFiles: *cacheSize,
+			Verify:         *verify,
+
+			Compressibility: *compressibility,
+			ZeroContent:     *zeroContent,
+			FileMode:        *chmod,
+			Sparse:          *sparse,
+			Tmpfs:           *tmpfs,
+			WriteRatio:      *writeRatio,
+			BimodalSwitch:   *bimodalSwitch,
+			BimodalCold:     *bimodalCold,
+
+			BackgroundWriters: *backgroundWriters,
+
+			DirDepth:    *dirDepth,
+			FilesPerDir: *filesPerDir,
+
+			Fsync:   *fsync,
+			ODirect: *oDirect,
+
+			FadviseSequential: *fadviseSeq,
+			PhaseTiming:       *phases,
+			CountSyscalls:     *countSyscalls,
+			Streaming:         *streaming,
+			ReadFraction:      *readFraction,
+			BlockAlign:        *blockAlign,
+			BlockMisalign:     *blockMisalign,
+			CompareAlignment:  *compareAlignment,
+
+			MissingRatio: *missingRatio,
+
+			Auto:          *auto,
+			MinIterations: *minIter,
+			MaxIterations: *maxIter,
+			TargetCV:      *targetCV,
+
+			Trim:             *trim,
+			RecordIterations: *perIter,
+		}
+	}
+
+	if *sweepWorkers != "" {
+		config.SweepWorkers = nil
+		for _, field := range strings.Split(*sweepWorkers, ",") {
+			w, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				errorf("Invalid -sweep-workers entry %q: %v\n", field, err)
+				os.Exit(1)
+			}
+			config.SweepWorkers = append(config.SweepWorkers, w)
+		}
+	}
+
+	if *patternNames != "" {
+		ids, err := parsePatternNames(*patternNames)
+		if err != nil {
+			errorf("Invalid -patterns: %v\n", err)
+			os.Exit(1)
+		}
+		config.ReadPatterns = patternSpecs(ids)
+	}
+
+	config.setDefaults()
+	if configData != nil {
+		warnStaleConfig(configData, config)
+	}
+	if err := config.Validate(); err != nil {
+		errorf("Invalid config: %v\n", err)
+		os.Exit(1)
+	}
+	var profileOnly string
+	if *profilePattern != "" {
+		ids, err := parsePatternNames(*profilePattern)
+		if err == nil && len(ids) != 1 {
+			err = fmt.Errorf("name a single pattern")
+		}
+		if err != nil {
+			errorf("Invalid -profile-pattern: %v\n", err)
+			os.Exit(1)
+		}
+		if *cpuProfile == "" && *traceOut == "" {
+			errorf("Warning: -profile-pattern has no effect without -cpuprofile or -trace\n")
+		}
+		if !config.hasPattern(ids[0]) {
+			errorf("Warning: -profile-pattern %s isn't one of the patterns being run\n", getPatternName(ids[0]))
+		}
+		profileOnly = getPatternName(ids[0])
+	}
+	if (*trend || *historyKeep != 0) && *historyPath == "" {
+		errorf("Error: -trend and -history-keep need -history\n")
+		os.Exit(1)
+	}
+	if *historyKeep < 0 {
+		errorf("Error: -history-keep must be >= 0, got %d\n", *historyKeep)
+		os.Exit(1)
+	}
+	if config.TraceFile != "" {
+		order, err := loadTrace(config.TraceFile, config)
+		if err != nil {
+			errorf("Error loading trace file: %v\n", err)
+			os.Exit(1)
+		}
+		config.traceOrder = order
+	}
+
+	levels := config.concurrencyLevels()
+	if slices.Max(levels) > 1 {
+		limit := "unknown"
+		if soft, ok := softOpenFileLimit(); ok {
+			limit = fmt.Sprint(soft)
+		}
+		logf("Concurrent readers may hold at most %d files open (soft open-file limit %s)\n", config.MaxOpenFiles, limit)
+	}
+
+	if *reuse && config.MissingRatio > 0 {
+		errorf("Error: -reuse can't be combined with -missing, it would delete files from the kept dataset\n")
+		os.Exit(1)
+	}
+	if *reuse && config.Backend == "gzip" {
+		errorf("Error: -reuse is not supported with the gzip backend\n")
+		os.Exit(1)
+	}
+
+	if config.ODirect && !directIOSupported {
+		errorf("Error: -odirect is unsupported on %s\n", runtime.GOOS)
+		os.Exit(1)
+	}
+
+	if config.Backend == "mmap" && !mmapSupported {
+		errorf("Error: the mmap backend is unsupported on %s\n", runtime.GOOS)
+		os.Exit(1)
+	}
+	if config.Backend == "iouring" && !ioUringSupported {
+		errorf("Error: the iouring backend needs Linux, it is unsupported on %s\n", runtime.GOOS)
+		os.Exit(1)
+	}
+	if config.Backend == "preadv" && !preadvSupported {
+		errorf("Error: the preadv backend needs Linux, it is unsupported on %s\n", runtime.GOOS)
+		os.Exit(1)
+	}
+
+	if config.Tmpfs && !tmpfsSupported {
+		errorf("Warning: -tmpfs is unsupported on %s, the files stay on the target directory's filesystem\n", runtime.GOOS)
+		config.Tmpfs = false
+	}
+
+	if config.DropCache && !coldCacheSupported {
+		errorf("Warning: cold-cache mode is unsupported on %s/%s, reads will be served from the page cache\n", runtime.GOOS, runtime.GOARCH)
+		config.DropCache = false
+	}
+	if config.FadviseSequential && !fadviseSupported {
+		errorf("Warning: fadvise is unsupported on %s/%s, reads will run without the sequential hint\n", runtime.GOOS, runtime.GOARCH)
+		config.FadviseSequential = false
+	}
+	if config.Isolation == "dropcache" && !coldCacheSupported {
+		errorf("Warning: dropping the page cache is unsupported on %s/%s, patterns will not be isolated\n", runtime.GOOS, runtime.GOARCH)
+		config.Isolation = "none"
+	}
+
+	results := BenchmarkResults{
+		Config:  config,
+		Results: []BenchmarkResult{},
+	}
+
+	hostname, _ := os.Hostname()
+	results.System.Hostname = hostname
+	results.System.Timestamp = time.Now().Format(time.RFC3339)
+	results.System.OS = runtime.GOOS
+	results.System.Arch = runtime.GOARCH
+	results.System.NumCPU = runtime.NumCPU()
+	results.System.CPUModel = cpuModel()
+	results.System.TotalRAM = totalMemoryBytes()
+	results.System.GoVersion = runtime.Version()
+
+	// Record the seed actually used so a time-seeded run can be replayed
+	runSeed := config.Seed
+	if runSeed == 0 {
+		runSeed = time.Now().UnixNano()
+	}
+	results.System.Seed = runSeed
+	config.zipfSeed = runSeed
+	results.System.CacheNote = cacheNote(config)
+	rng := rand.New(rand.NewSource(runSeed))
+
+	sizes := fileSizes(config, rng)
+	results.Dataset = datasetStats(sizes)
+
+	if *dryRun {
+		printDryRun(config, sizes, rng)
+		return
+	}
+
+	if config.Tmpfs && !*force {
+		// A tmpfs defaults to half the RAM and can't hold more
+		if ram := totalMemoryBytes(); ram > 0 && uint64(results.Dataset.TotalBytes) > ram/2 {
+			errorf("Error: the %d MB dataset won't fit in a tmpfs on %d MB of RAM, pass -force to try anyway\n", results.Dataset.TotalBytes>>20, ram>>20)
+			os.Exit(1)
+		}
+	}
+	if !*force && !*reuse && !config.Sparse && !config.Tmpfs {
+		if err := checkFreeSpace(config.TargetDirectory, results.Dataset.TotalBytes); err != nil {
+			errorf("Error: %v, pass -force to generate them anyway\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// The first SIGINT/SIGTERM lets the current iteration finish so partial
+	// results can be written, a second one exits immediately
+	stop := make(chan struct{})
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		errorf("\nInterrupted, stopping after the current iteration (interrupt again to force exit)\n")
+		close(stop)
+		<-sigCh
+		errorf("\nForced exit\n")
+		os.Exit(130)
+	}()
+
+	if *traceOut != "" {
+		errorf("Warning: execution tracing adds overhead to every read, timings aren't comparable with untraced runs\n")
+	}
+
+	runner := &benchRunner{
+		config: config,
+		rng:    rng,
+		keep:   *keep,
+		stop:   stop,
+
+		reuse:      *reuse,
+		regenerate: *regenerate,
+
+		progress: isTerminal(os.Stdout) && currentLevel == levelNormal,
+		profiler: newProfiler(*cpuProfile, *memProfile, *traceOut, profileOnly),
+		results:  &results,
+
+		manifestPath: *manifestPath,
+
+		dumpDir:   *dumpOrder,
+		dumpLimit: *dumpLimit,
+
+		started:    time.Now(),
+		unitsTotal: config.Runs * len(config.TargetDirectory) * len(levels) * config.passes(),
+	}
+
+	if *manifestPath != "" {
+		manifest := &Manifest{Seed: runSeed}
+		if *reuse {
+			var err error
+			manifest, err = loadManifest(*manifestPath, runSeed)
+			if err != nil {
+				errorf("Error reading manifest: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		runner.manifest = manifest
+	}
+
+	if *pushGatewayURL != "" {
+		gateway := newPushGateway(*pushGatewayURL, hostname)
+		runner.onResult = append(runner.onResult, func(result BenchmarkResult) {
+			if err := gateway.push(result); err != nil {
+				errorf("Warning: failed to push %s metrics: %v\n", result.Pattern, err)
+			}
+		})
+	}
+
+	var jsonl *jsonlWriter
+	if *jsonlPath != "" {
+		var err error
+		jsonl, err = newJSONLWriter(*jsonlPath)
+		if err != nil {
+			errorf("Error opening JSON Lines output %s: %v\n", *jsonlPath, err)
+			os.Exit(1)
+		}
+		runner.onResult = append(runner.onResult, func(result BenchmarkResult) {
+			if err := jsonl.write(result); err != nil {
+				errorf("Warning: failed to append %s to %s: %v\n", result.Pattern, *jsonlPath, err)
+			}
+		})
+	}
+
+	var server *http.Server
+	if *httpAddr != "" {
+		server = startHTTPServer(*httpAddr, runner)
+	}
+
+	suiteStart := time.Now()
+	for _, dir := range config.TargetDirectory {
+		if runner.interrupted() {
+			break
+		}
+		if err := runner.runSuite(dir, sizes); err != nil {
+			errorf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	runner.profiler.finish()
+	if server != nil {
+		stopHTTPServer(server)
+	}
+	if jsonl != nil {
+		if err := jsonl.close(); err != nil {
+			errorf("Warning: failed to close %s: %v\n", *jsonlPath, err)
+		}
+	}
+	results.InProgress = nil
+	results.TotalDuration = time.Since(suiteStart)
+	results.TotalReads = runner.totalReads
+
+	if runner.interrupted() {
+		results.Partial = true
+	}
+	if config.Runs > 1 {
+		results.Aggregates = aggregateRuns(results.Results)
+	}
+
+	resultData, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		errorf("Error serializing results: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = writeFileGz(*outputPath, resultData)
+	if err != nil {
+		errorf("Error writing results to %s: %v\n", *outputPath, err)
+		os.Exit(1)
+	}
+
+	if *csvPath != "" {
+		if err := writeCSV(*csvPath, results); err != nil {
+			errorf("Error writing CSV results to %s: %v\n", *csvPath, err)
+			os.Exit(1)
+		}
+	}
+
+	if *mdPath != "" {
+		if err := writeMarkdown(*mdPath, results); err != nil {
+			errorf("Error writing Markdown report to %s: %v\n", *mdPath, err)
+			os.Exit(1)
+		}
+	}
+
+	if *influxPath != "" {
+		if err := writeInflux(*influxPath, results); err != nil {
+			errorf("Error writing InfluxDB line protocol to %s: %v\n", *influxPath, err)
+			os.Exit(1)
+		}
+	}
+	if *influxURL != "" {
+		if err := postInflux(*influxURL, results); err != nil {
+			errorf("Warning: failed to post results to InfluxDB: %v\n", err)
+		}
+	}
+
+	var history []BenchmarkResults
+	if *historyPath != "" {
+		var err error
+		history, err = appendHistory(*historyPath, results, *historyKeep)
+		if err != nil {
+			errorf("Error appending results to history %s: %v\n", *historyPath, err)
+			os.Exit(1)
+		}
+	}
+
+	logf("Benchmark complete. Results saved to %s\n", *outputPath)
+
+	if config.Runs > 1 {
+		printRunSummary(results)
+	} else if len(config.SweepWorkers) > 0 {
+		printSweepSummary(results)
+	} else {
+		printSummary(results)
+	}
+	if *chart {
+		printChart(results)
+	}
+	if *trend {
+		printTrend(history)
+	}
+
+	if *comparePath != "" {
+		baseline, err := loadResults(*comparePath)
+		if err != nil {
+			errorf("Error loading baseline: %v\n", err)
+			os.Exit(1)
+		}
+		if regressions := compareResults(baseline, results, *threshold); len(regressions) > 0 {
+			os.Exit(exitRegression)
+		}
+	}
+}
+
+// maxDiskFraction is how much of the free space a generated dataset may use
+const maxDiskFraction = 0.9
+
+// checkFreeSpace fails when the datasets of size bytes generated in each of
+// dirs would take more than maxDiskFraction of the free space on a
+// filesystem. Directories on the same filesystem count together. A dir may
+// not exist yet, so its nearest existing ancestor is checked. Platforms
+// that can't report free space pass.
+func checkFreeSpace(dirs []string, size int64) error {
+	type filesystem struct {
+		path  string
+		total int64
+	}
+	var order []string
+	byFS := make(map[string]*filesystem)
+	for _, dir := range dirs {
+		path := filepath.Clean(dir)
+		for {
+			if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+				break
+			}
+			path = filepath.Dir(path)
+		}
+		key := "path " + path
+		if dev, ok := filesystemID(path); ok {
+			key = fmt.Sprintf("dev %d", dev)
+		}
+		if byFS[key] == nil {
+			byFS[key] = &filesystem{path: path}
+			order = append(order, key)
+		}
+		byFS[key].total += size
+	}
+
+	for _, key := range order {
+		fsys := byFS[key]
+		free, ok := freeDiskSpace(fsys.path)
+		if !ok {
+			continue
+		}
+		if float64(fsys.total) > float64(free)*maxDiskFraction {
+			return fmt.Errorf("the %.2f MB of datasets would use more than %.0f%% of the %.2f MB free on %s",
+				float64(fsys.total)/1024/1024, maxDiskFraction*100, float64(free)/1024/1024, fsys.path)
+		}
+	}
+	return nil
+}
+
+// printDryRun reports the dataset and per-pattern workload that a real run
+// would produce, generating access orders but never touching the disk
+func printDryRun(config BenchmarkConfig, sizes []int64, rng *rand.Rand) {
+	stats := datasetStats(sizes)
+	fmt.Printf("Dry run: %d files (%.2f MB) per directory, %d directories, %.2f MB total disk usage\n",
+		len(sizes), float64(stats.TotalBytes)/1024/1024, len(config.TargetDirectory),
+		float64(stats.TotalBytes)*float64(len(config.TargetDirectory))/1024/1024)
+	fmt.Printf("File size min %d / avg %d / max %d bytes\n", stats.MinFileSize, stats.AvgFileSize, stats.MaxFileSize)
+
+	files := make([]FileInfo, len(sizes))
+	for i, size := range sizes {
+		files[i] = FileInfo{Path: testFilePath(config.TargetDirectory[0], i, config), Size: size}
+	}
+
+	fmt.Println("\nPattern               | Reads/iter | Unique files | MB/iter  | Total reads")
+	fmt.Println("----------------------|------------|--------------|----------|------------")
+	for _, spec := range config.ReadPatterns {
+		patternID := spec.ID
+		passes := config.forPattern(spec).iterationLimit() + config.Warmup
+		order := buildAccessSeq(files, patternID, rng, config)
+
+		unique := make(map[int]bool)
+		var bytes int64
+		for _, idx := range order.base {
+			unique[idx] = true
+			bytes += files[idx].Size * int64(order.repeats)
+		}
+		fmt.Printf("%-20s | %10d | %12d | %8.2f | %11d\n",
+			getPatternName(patternID), order.len(), len(unique),
+			float64(bytes)/1024/1024, order.len()*passes*len(config.TargetDirectory))
+	}
+}
+
+// benchRunner holds the state shared by every suite run in one invocation
+type benchRunner struct {
+	config BenchmarkConfig
+	rng    *rand.Rand
+	keep   bool
+	stop   chan struct{}
+
+	reuse      bool
+	regenerate bool
+
+	// manifest lists the files, sizes and checksums of the dataset, it's
+	// read back with -reuse to verify against what was originally written
+	manifest     *Manifest
+	manifestPath string
+
+	// Every pattern's access orders go to their own file in dumpDir,
+	// capped at dumpLimit accesses. dumps numbers the files.
+	dumpDir   string
+	dumpLimit int
+	dumps     int
+
+	// progress rewrites a single status line per pattern instead of
+	// logging every iteration, only used when stdout is a terminal
+	progress bool
+
+	totalReads int64
+	profiler   *profiler
+
+	// The suite's start time and its warmup plus measured iterations,
+	// total and completed, drive the ETA estimate
+	started    time.Time
+	unitsTotal int
+	unitsDone  int
+
+	// results is shared with the live HTTP endpoint, guard it with mu
+	mu         sync.Mutex
+	results    *BenchmarkResults
+	currentDir string
+
+	// missing holds the indices of files deleted for MissingRatio
+	missing []int
+
+	// onResult hooks run after every completed pattern
+	onResult []func(BenchmarkResult)
+}
+
+// completeUnit counts one finished iteration and estimates the time left
+// from the average so far
+func (r *benchRunner) completeUnit() time.Duration {
+	r.unitsDone++
+	remaining := r.unitsTotal - r.unitsDone
+	if remaining <= 0 {
+		return 0
+	}
+	avg := time.Since(r.started) / time.Duration(r.unitsDone)
+	return (avg * time.Duration(remaining)).Round(time.Second)
+}
+
+func (r *benchRunner) addResult(result BenchmarkResult) {
+	r.mu.Lock()
+	r.results.Results = append(r.results.Results, result)
+	r.results.InProgress = nil
+	r.mu.Unlock()
+
+	for _, hook := range r.onResult {
+		hook(result)
+	}
+}
+
+func (r *benchRunner) setProgress(pattern string, iteration, iterations int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results.InProgress = &PatternProgress{
+		Directory:  r.currentDir,
+		Pattern:    pattern,
+		Iteration:  iteration,
+		Iterations: iterations,
+	}
+}
+
+// snapshot marshals the results gathered so far
+func (r *benchRunner) snapshot() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return json.MarshalIndent(r.results, "", "  ")
+}
+
+func (r *benchRunner) interrupted() bool {
+	select {
+	case <-r.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// runSuite generates the dataset in dir, runs every configured pattern
+// against it and cleans up afterwards
+func (r *benchRunner) runSuite(dir string, sizes []int64) error {
+	config := r.config
+	r.currentDir = dir
+
+	if config.Tmpfs {
+		mounted, created, err := r.ensureTmpfs(dir)
+		if err != nil {
+			return err
+		}
+		if mounted {
+			defer r.releaseTmpfs(dir, created)
+		}
+	}
+
+	files, createdDir, err := r.prepareFiles(dir, sizes)
+	if err != nil {
+		return err
+	}
+	if fsType, ok := filesystemType(dir); ok {
+		logf("Filesystem: %s\n", fsType)
+		r.mu.Lock()
+		if r.results.System.Filesystems == nil {
+			r.results.System.Filesystems = make(map[string]string)
+		}
+		r.results.System.Filesystems[dir] = fsType
+		r.mu.Unlock()
+	}
+	if config.MissingRatio > 0 {
+		r.missing = pickMissing(len(files), config.MissingRatio, r.rng)
+		if err := removeFiles(files, r.missing); err != nil {
+			return fmt.Errorf("removing files for missingRatio: %w", err)
+		}
+		logf("Deleted %d of %d files to simulate missing data\n", len(r.missing), len(files))
+	}
+	stats := datasetStats(sizes)
+	logf("Dataset: %.2f MB total, file size min %d / avg %d / max %d bytes\n",
+		float64(stats.TotalBytes)/1024/1024, stats.MinFileSize, stats.AvgFileSize, stats.MaxFileSize)
+
+	patterns := append([]PatternSpec(nil), config.ReadPatterns...)
+	if config.ShufflePatterns {
+		r.rng.Shuffle(len(patterns), func(i, j int) {
+			patterns[i], patterns[j] = patterns[j], patterns[i]
+		})
+	}
+
+	runs := 0
+suite:
+	for run := 1; run <= config.Runs; run++ {
+		if config.Runs > 1 {
+			if run > 1 && !r.interrupted() {
+				r.isolate(config.RunIsolation, dir, sizes, files)
+			}
+			logf("Run %d/%d\n", run, config.Runs)
+		}
+		for _, spec := range patterns {
+			patternID := spec.ID
+			for _, workers := range config.concurrencyLevels() {
+				if r.interrupted() {
+					break suite
+				}
+				if runs > 0 {
+					r.isolate(config.Isolation, dir, sizes, files)
+				}
+				runConfig := config.forPattern(spec)
+				runConfig.Concurrency = workers
+				var scalar BenchmarkResult
+				if runConfig.comparesScalar(patternID) {
+					scalarConfig := runConfig.scalarBaseline()
+					logf("Scalar baseline reading %d KB blocks with ReadAt:\n", runConfig.IovecKB)
+					var ok bool
+					scalar, ok = r.runPattern(files, patternID, scalarConfig)
+					if !ok || r.interrupted() {
+						break suite
+					}
+					r.isolate(config.Isolation, dir, sizes, files)
+				}
+				var baseline BenchmarkResult
+				if runConfig.withBackgroundWriters(patternID) {
+					baseConfig := runConfig
+					baseConfig.BackgroundWriters = 0
+					logf("Baseline without background writers:\n")
+					var ok bool
+					baseline, ok = r.runPattern(files, patternID, baseConfig)
+					if !ok || r.interrupted() {
+						break suite
+					}
+					r.isolate(config.Isolation, dir, sizes, files)
+				}
+				result, ok := r.runPattern(files, patternID, runConfig)
+				if !ok {
+					break suite
+				}
+				if runConfig.withBackgroundWriters(patternID) && baseline.MBytesPerSec > 0 {
+					result.BaselineMBytesPerSec = baseline.MBytesPerSec
+					result.WriterSlowdown = (1 - result.MBytesPerSec/baseline.MBytesPerSec) * 100
+					logf("  %.2f MB/s with %d background writers vs %.2f MB/s without (%+.1f%%)\n",
+						result.MBytesPerSec, runConfig.BackgroundWriters, baseline.MBytesPerSec, -result.WriterSlowdown)
+				}
+				if runConfig.comparesScalar(patternID) && scalar.MBytesPerSec > 0 {
+					result.ScalarMBytesPerSec = scalar.MBytesPerSec
+					result.VectoredSpeedup = (result.MBytesPerSec/scalar.MBytesPerSec - 1) * 100
+					logf("  %.2f MB/s with preadv vs %.2f MB/s with scalar %d KB reads (%+.1f%%)\n",
+						result.MBytesPerSec, scalar.MBytesPerSec, runConfig.IovecKB, result.VectoredSpeedup)
+				}
+				if runConfig.comparesAlignment(patternID) && !r.interrupted() {
+					r.isolate(config.Isolation, dir, sizes, files)
+					misConfig := runConfig
+					misConfig.BlockMisalign = true
+					logf("Again with block offsets misaligned by %d bytes:\n", runConfig.BlockAlign/2)
+					misaligned, ok := r.runPattern(files, patternID, misConfig)
+					if !ok {
+						break suite
+					}
+					if result.MBytesPerSec > 0 {
+						result.MisalignedMBytesPerSec = misaligned.MBytesPerSec
+						result.MisalignSlowdown = (1 - misaligned.MBytesPerSec/result.MBytesPerSec) * 100
+						logf("  %.2f MB/s aligned to %d bytes vs %.2f MB/s misaligned (%+.1f%%)\n",
+							result.MBytesPerSec, runConfig.BlockAlign, misaligned.MBytesPerSec, -result.MisalignSlowdown)
+					}
+				}
+				runs++
+				result.Directory = dir
+				result.RunOrder = runs
+				if config.Runs > 1 {
+					result.Run = run
+				}
+				r.addResult(result)
+			}
+
+			// Overwriting writes replace the contents the checksums describe
+			if config.Verify && writesFiles(patternID) && !config.WriteNewFiles {
+				if err := checksumFiles(files); err != nil {
+					errorf("Warning: failed to checksum rewritten files: %v\n", err)
+				}
+			}
+		}
+	}
+
+	if r.keep || r.reuse {
+		logf("Keeping benchmark files in %s\n", dir)
+	} else {
+		logf("Cleaning up...\n")
+		cleanupFiles(files, dir, createdDir)
+	}
+	return nil
+}
+
+// ensureTmpfs mounts a tmpfs on dir unless it already is one. It reports
+// whether it mounted one and the directory it had to create for it, if any.
+func (r *benchRunner) ensureTmpfs(dir string) (bool, string, error) {
+	if isTmpfs(dir) {
+		logf("%s is already a tmpfs\n", dir)
+		return false, "", nil
+	}
+	created, err := createTargetDir(dir)
+	if err != nil {
+		return false, "", fmt.Errorf("creating target directory: %w", err)
+	}
+	if err := mountTmpfs(dir); err != nil {
+		if created != "" {
+			os.RemoveAll(created)
+		}
+		return false, "", err
+	}
+	logf("Mounted a tmpfs on %s\n", dir)
+	return true, created, nil
+}
+
+// releaseTmpfs unmounts the tmpfs ensureTmpfs mounted on dir, unless the
+// files are being kept
+func (r *benchRunner) releaseTmpfs(dir, created string) {
+	if r.keep || r.reuse {
+		logf("Leaving the tmpfs on %s mounted, unmount it with umount %s\n", dir, dir)
+		return
+	}
+	if err := unmountTmpfs(dir); err != nil {
+		errorf("Warning: failed to unmount the tmpfs on %s: %v\n", dir, err)
+		return
+	}
+	if created != "" {
+		os.RemoveAll(created)
+	}
+}
+
+// contentRng returns a generator for file content seeded from the run's
+// seed, independent of how much of r.rng the run has used
+func (r *benchRunner) contentRng() *rand.Rand {
+	return rand.New(rand.NewSource(r.results.System.Seed))
+}
+
+// isolate resets the cache state left behind by the previous pattern or run
+// as mode ("none", "dropcache" or "regenerate") asks
+func (r *benchRunner) isolate(mode, dir string, sizes []int64, files []FileInfo) {
+	switch mode {
+	case "dropcache":
+		if err := dropPageCache(files); err != nil {
+			errorf("Warning: failed to drop page cache: %v\n", err)
+		}
+	case "regenerate":
+		logf("Regenerating files in %s...\n", dir)
+		regenerated, err := createTestFiles(dir, sizes, r.config, r.contentRng())
+		if err != nil {
+			errorf("Warning: failed to regenerate files: %v\n", err)
+			return
+		}
+		copy(files, regenerated)
+		if err := removeFiles(files, r.missing); err != nil {
+			errorf("Warning: failed to remove missing files after regenerating: %v\n", err)
+		}
+	}
+}
+
+// cacheNote describes how patterns in this run can affect each other's
+// cache state so the recorded numbers can be interpreted
+func cacheNote(config BenchmarkConfig) string {
+	var note string
+	switch config.Isolation {
+	case "dropcache":
+		note = "page cache dropped between patterns"
+	case "regenerate":
+		note = "files rewritten between patterns, freshly written data may still be cached"
+	default:
+		note = "patterns share one dataset, later patterns may read data cached by earlier ones"
+	}
+	if config.ShufflePatterns {
+		note += "; pattern order shuffled per suite, see runOrder"
+	}
+	if config.DropCache {
+		note += "; page cache dropped before every iteration"
+	}
+	if config.Tmpfs {
+		note += "; files held in tmpfs, no disk involved"
+	}
+	if config.Runs > 1 {
+		note += fmt.Sprintf("; suite run %d times over one dataset, isolation between runs: %s", config.Runs, config.RunIsolation)
+	}
+	return note
+}
+
+// prepareFiles creates the dataset in dir, or with -reuse picks up a
+// matching dataset left there by an earlier run. It reports the directory it
+// created, if any.
+func (r *benchRunner) prepareFiles(dir string, sizes []int64) ([]FileInfo, string, error) {
+	config := r.config
+
+	if r.reuse {
+		files, err := loadExistingFiles(dir, sizes, config)
+		if err == nil && r.manifest != nil && r.manifest.loaded {
+			err = r.manifest.apply(files)
+		} else if err == nil && (config.Verify || r.manifest != nil) {
+			// There's no record of what was written, so trust the contents
+			// as they are now
+			err = checksumFiles(files)
+		}
+		if err == nil {
+			logf("Reusing %d existing files in %s\n", len(files), dir)
+			return files, "", r.recordManifest(files)
+		}
+		if !r.regenerate {
+			return nil, "", fmt.Errorf("existing files in %s don't match the config: %v (pass -regenerate to recreate them)", dir, err)
+		}
+		logf("Existing files in %s don't match the config (%v), regenerating\n", dir, err)
+	}
+
+	createdDir, err := createTargetDir(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating target directory: %w", err)
+	}
+
+	if config.SizeDistribution == "" || config.SizeDistribution == "fixed" {
+		logf("Creating %d files of %d KB each in %s...\n", config.NumFiles, config.FileSizeKB, dir)
+	} else {
+		logf("Creating %d files with %s sizes in %s...\n", config.NumFiles, config.SizeDistribution, dir)
+	}
+	files, err := createTestFiles(dir, sizes, config, r.contentRng())
+	if err != nil {
+		return nil, "", fmt.Errorf("creating test files: %w", err)
+	}
+	if r.manifest != nil && config.Sparse && !config.Verify {
+		// Sparse files skip the checksum unless verifying
+		if err := checksumFiles(files); err != nil {
+			return nil, "", fmt.Errorf("checksumming test files: %w", err)
+		}
+	}
+	if r.manifest != nil {
+		r.manifest.Seed = r.results.System.Seed
+	}
+	return files, createdDir, r.recordManifest(files)
+}
+
+// dumpOrders writes a pattern's access orders for -dump-order, failures
+// only warn since the results don't depend on them
+func (r *benchRunner) dumpOrders(files []FileInfo, patternName string, workers int, orders [][]int) {
+	if err := os.MkdirAll(r.dumpDir, 0755); err != nil {
+		errorf("Warning: failed to create %s: %v\n", r.dumpDir, err)
+		return
+	}
+	r.dumps++
+	dump := newOrderDump(files, orders, r.dumpLimit)
+	dump.Pattern = patternName
+	dump.Directory = r.currentDir
+	dump.Concurrency = workers
+	path := filepath.Join(r.dumpDir, fmt.Sprintf("%03d-%s-w%d.json", r.dumps, patternKey(patternName), workers))
+	if err := writeOrderDump(path, dump); err != nil {
+		errorf("Warning: failed to write access order: %v\n", err)
+		return
+	}
+	if dump.Truncated {
+		logf("  Access order written to %s (cut to %d accesses)\n", path, r.dumpLimit)
+	} else {
+		verbosef("  Access order written to %s\n", path)
+	}
+}
+
+// recordManifest adds files to the manifest, if one was requested, and
+// rewrites it so it's complete even if the run is cut short
+func (r *benchRunner) recordManifest(files []FileInfo) error {
+	if r.manifest == nil {
+		return nil
+	}
+	r.manifest.add(files)
+	if err := r.manifest.write(r.manifestPath); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	return nil
+}
+
+// runPattern runs the warmup and measured iterations of one pattern and
+// aggregates them. It returns false if interrupted before any measurement.
+func (r *benchRunner) runPattern(files []FileInfo, patternID int, config BenchmarkConfig) (BenchmarkResult, bool) {
+	patternName := getPatternName(patternID)
+	limit := config.iterationLimit()
+	iterations := fmt.Sprintf("%d iterations", limit)
+	if config.Auto {
+		iterations = fmt.Sprintf("%d-%d iterations", config.MinIterations, limit)
+	}
+	if len(config.SweepWorkers) > 0 {
+		logf("Running benchmark for %s pattern (%s, %d workers)...\n", patternName, iterations, config.Concurrency)
+	} else {
+		logf("Running benchmark for %s pattern (%s)...\n", patternName, iterations)
+	}
+
+	shared := newRunResources(files, config)
+
+	// Warmup passes generate and run the pattern like a measured
+	// iteration but their numbers are thrown away
+	warmedUp := 0
+	for i := 0; i < config.Warmup && !r.interrupted(); i++ {
+		_, err := runBenchmark(files, patternID, r.rng, config, shared)
+		r.completeUnit()
+		if err != nil {
+			errorf("Error during warmup: %v\n", err)
+			continue
+		}
+		warmedUp++
+	}
+	if config.Warmup > 0 {
+		logf("  Completed %d/%d warmup passes\n", warmedUp, config.Warmup)
+	}
+
+	var totalDuration time.Duration
+	var totalBytes int64
+	var latencies []time.Duration
+	var histogram latencyHistogram
+	var durations []time.Duration
+	var iterBytes, iterOps, iterIOs []int64
+	var totalWall time.Duration
+	var lastErr error
+	var totalOps int64
+	var totalHits int64
+	var totalMissing, totalTimeouts, totalRetries int64
+	var totalRetryTime time.Duration
+	var totalSyscalls syscallCounter
+	var totalMix mixStats
+	var totalPhases phaseStats
+	var throughput [][]int64
+	var workerLoads []WorkerLoad
+	var orders [][]int
+	var orderLen int
+	touched := make([]bool, len(files))
+	successful := 0
+	convergedCV := -1.0
+
+	var writers *backgroundWriters
+	if config.withBackgroundWriters(patternID) {
+		logf("  Started %d background writers\n", config.BackgroundWriters)
+		writers = startBackgroundWriters(files, config.BackgroundWriters, r.rng, config.Fsync)
+	}
+
+	var memBefore, memAfter runtime.MemStats
+	runtime.ReadMemStats(&memBefore)
+	for i := 0; i < limit && !r.interrupted(); i++ {
+		r.setProgress(patternName, i+1, limit)
+		if !r.progress {
+			logf("  Iteration %d/%d...\n", i+1, limit)
+		}
+		if config.DropCache {
+			if err := dropPageCache(files); err != nil {
+				errorf("Warning: failed to drop page cache: %v\n", err)
+			}
+		}
+		var iter IterationResult
+		var err error
+		r.profiler.measure(patternName, func() {
+			iter, err = runBenchmark(files, patternID, r.rng, config, shared)
+		})
+		eta := r.completeUnit()
+		if err != nil {
+			errorf("Error running benchmark: %v\n", err)
+			lastErr = err
+			continue
+		}
+		successful++
+		totalDuration += iter.Duration
+		durations = append(durations, iter.Duration)
+		iterBytes = append(iterBytes, iter.BytesRead)
+		totalWall += iter.WallTime
+		iterOps = append(iterOps, iter.Ops)
+		iterIOs = append(iterIOs, iter.IOs)
+		totalBytes += iter.BytesRead
+		latencies = append(latencies, iter.Latencies...)
+		histogram.merge(iter.Histogram)
+		if iter.Throughput != nil {
+			throughput = append(throughput, iter.Throughput)
+		}
+		totalOps += iter.Ops
+		totalHits += iter.CacheHits
+		totalMissing += iter.ErrorCount
+		totalTimeouts += iter.Timeouts
+		totalRetries += iter.Retries
+		totalRetryTime += iter.RetryTime
+		totalSyscalls.merge(iter.Syscalls)
+		totalMix.merge(iter.Mix)
+		totalPhases.merge(iter.Phases)
+		for _, idx := range iter.Order.base {
+			touched[idx] = true
+		}
+		if r.dumpDir != "" && (r.dumpLimit == 0 || orderLen < r.dumpLimit) {
+			order := iter.Order.expand(r.dumpLimit - orderLen)
+			orders = append(orders, order)
+			orderLen += len(order)
+		}
+		if workerLoads == nil && iter.Workers != nil {
+			workerLoads = make([]WorkerLoad, len(iter.Workers))
+		}
+		for w, load := range iter.Workers {
+			workerLoads[w].Reads += load.Reads
+			workerLoads[w].Bytes += load.Bytes
+		}
+		r.totalReads += iter.Ops
+
+		if r.progress && totalDuration > 0 {
+			logf("\r\033[K  %s: iteration %d/%d, %.2f MB/s, suite ETA %s",
+				patternName, i+1, limit,
+				float64(totalBytes)/1024/1024/totalDuration.Seconds(), eta)
+		} else if !r.progress && eta > 0 {
+			logf("  Suite ETA: %s\n", eta)
+		}
+
+		if config.Auto && successful >= config.MinIterations {
+			cv := coefficientOfVariation(durations[len(durations)-config.MinIterations:])
+			if cv < config.TargetCV {
+				convergedCV = cv
+				// The skipped iterations no longer count toward the ETA
+				r.unitsTotal -= limit - (i + 1)
+				break
+			}
+		}
+	}
+	runtime.ReadMemStats(&memAfter)
+	var backgroundWrites, backgroundBytes int64
+	if writers != nil {
+		var err error
+		backgroundWrites, backgroundBytes, err = writers.finish()
+		if err != nil {
+			errorf("Warning: a background writer stopped early: %v\n", err)
+		}
+	}
+	if r.progress {
+		logf("\n")
+	}
+	if r.dumpDir != "" && len(orders) > 0 {
+		r.dumpOrders(files, patternName, config.Concurrency, orders)
+	}
+	if convergedCV >= 0 {
+		logf("  Converged after %d iterations (CV %.3f < %.3f)\n", successful, convergedCV, config.TargetCV)
+	} else if config.Auto && !r.interrupted() {
+		logf("  Did not converge within %d iterations\n", limit)
+	}
+
+	result := BenchmarkResult{
+		Pattern:     patternName,
+		FileCount:   len(files),
+		Concurrency: config.Concurrency,
+		Backend:     config.Backend,
+		BlockSizeKB: config.BlockSizeKB,
+		BlockAlign:  config.BlockAlign,
+		Misaligned:  config.BlockMisalign,
+		Iterations:  successful,
+	}
+	if config.Backend == "bufio" {
+		result.BufSizeKB = config.BufSizeKB
+	}
+	if config.Backend == "iouring" {
+		result.QueueDepth = config.QueueDepth
+	}
+	if config.Backend == "preadv" {
+		result.IovecKB = config.IovecKB
+	}
+	if patternID == PatternTraceReplay {
+		result.TraceFile = config.TraceFile
+	}
+	result.FadviseSequential = adviseSequential(patternID, config)
+	result.Tmpfs = config.Tmpfs
+	if writers != nil {
+		result.BackgroundWriters = config.BackgroundWriters
+		result.BackgroundWrites = backgroundWrites
+		result.BackgroundWriteBytes = backgroundBytes
+	}
+	if !isWritePattern(patternID) {
+		result.ReadFraction = config.ReadFraction
+	}
+	result.Streaming = config.Streaming
+	result.AllocBytes = memAfter.TotalAlloc - memBefore.TotalAlloc
+	result.Mallocs = memAfter.Mallocs - memBefore.Mallocs
+	result.NumGC = memAfter.NumGC - memBefore.NumGC
+	result.GCPauseTime = time.Duration(memAfter.PauseTotalNs - memBefore.PauseTotalNs)
+
+	if successful == 0 && r.interrupted() {
+		return result, false
+	}
+	if successful == 0 {
+		result.Error = lastErr.Error()
+		logf("  Result: failed, no iteration succeeded\n")
+		return result, true
+	}
+
+	// Only successful iterations contribute to the averages, minus any
+	// trimmed outliers
+	kept := trimIterations(durations, config.Trim)
+	result.TrimmedIterations = successful - len(kept)
+	var keptDuration time.Duration
+	var keptBytes, keptOps, keptIOs int64
+	for _, i := range kept {
+		keptDuration += durations[i]
+		keptBytes += iterBytes[i]
+		keptOps += iterOps[i]
+		keptIOs += iterIOs[i]
+	}
+	if config.RecordIterations {
+		result.PerIteration = make([]IterationSample, successful)
+		for i := range result.PerIteration {
+			result.PerIteration[i] = IterationSample{Duration: durations[i], BytesRead: iterBytes[i], Ops: iterOps[i], Trimmed: true}
+		}
+		for _, i := range kept {
+			result.PerIteration[i].Trimmed = false
+		}
+	}
+	result.Duration = keptDuration / time.Duration(len(kept))
+	if config.pacing().active() {
+		result.WallDuration = totalWall / time.Duration(successful)
+	}
+	result.BytesRead = keptBytes / int64(len(kept))
+	if result.Duration > 0 {
+		opsPerIteration := float64(keptOps) / float64(len(kept))
+		result.IOPS = float64(keptIOs) / float64(len(kept)) / result.Duration.Seconds()
+		result.ReadPerSec = opsPerIteration / result.Duration.Seconds()
+		result.MBytesPerSec = float64(result.BytesRead) / 1024 / 1024 / result.Duration.Seconds()
+	}
+	if keptIOs > 0 {
+		result.AvgReadBytes = keptBytes / keptIOs
+	}
+
+	result.MinDuration, result.MaxDuration, result.StdDevMs = durationStats(durations)
+
+	for _, t := range touched {
+		if t {
+			result.UniqueFiles++
+		}
+	}
+	if len(files) > 0 {
+		result.CoveragePercent = float64(result.UniqueFiles) / float64(len(files)) * 100
+	}
+	if successful > 0 {
+		result.NeverAccessed = untouchedRanges(touched)
+	}
+
+	// Percentiles are taken over the merged samples of every iteration
+	if config.Streaming {
+		result.P50 = histogram.percentile(50)
+		result.P95 = histogram.percentile(95)
+		result.P99 = histogram.percentile(99)
+		result.MaxLatency = histogram.percentile(100)
+	} else {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		result.P50 = percentile(latencies, 50)
+		result.P95 = percentile(latencies, 95)
+		result.P99 = percentile(latencies, 99)
+		result.MaxLatency = percentile(latencies, 100)
+	}
+
+	if config.ThroughputWindowMs > 0 {
+		result.Throughput = &ThroughputSeries{WindowMs: config.ThroughputWindowMs, Bytes: throughput}
+	}
+	if patternID == PatternMixed {
+		result.ReadMBytesPerSec, result.WriteMBytesPerSec = totalMix.throughput()
+	}
+	if config.PhaseTiming && !isWritePattern(patternID) {
+		result.OpenLatency, result.StatLatency, result.ReadLatency, result.CloseLatency = totalPhases.means()
+	}
+	result.MissingReads = totalMissing
+	result.TimedOutReads = totalTimeouts
+	result.Retries = totalRetries
+	result.RetryTime = totalRetryTime
+	if config.CountSyscalls && totalOps > 0 {
+		result.Syscalls = totalSyscalls.totals()
+		result.SyscallsPerOp = float64(totalSyscalls.total()) / float64(totalOps)
+	}
+	if len(workerLoads) > 0 {
+		result.WorkerLoads = workerLoads
+		workerBytes := make([]int64, len(workerLoads))
+		for w, load := range workerLoads {
+			workerBytes[w] = load.Bytes
+		}
+		result.Fairness = jainIndex(workerBytes)
+	}
+	if config.CacheSizeFiles > 0 && totalOps > 0 {
+		hitRatio := float64(totalHits) / float64(totalOps)
+		result.HitRatio = &hitRatio
+	}
+
+	logf("  Result: %.2f MB/s, %.2f files/s\n", result.MBytesPerSec, result.ReadPerSec)
+	if config.BlockSizeKB > 0 {
+		logf("  %.0f IOPS, %d bytes per read\n", result.IOPS, result.AvgReadBytes)
+	}
+	if result.WallDuration > 0 {
+		logf("  Wall clock per iteration including pauses: %v\n", result.WallDuration)
+	}
+	if result.TrimmedIterations > 0 {
+		logf("  Trimmed %d outlier iterations from the averages\n", result.TrimmedIterations)
+	}
+	logf("  Files touched: %d of %d (%.1f%% coverage)\n", result.UniqueFiles, len(files), result.CoveragePercent)
+	if result.NeverAccessed != "" {
+		verbosef("  Never accessed: %s\n", result.NeverAccessed)
+	}
+	if patternID == PatternMixed {
+		logf("  Reads: %.2f MB/s (%d ops), writes: %.2f MB/s (%d ops)\n",
+			result.ReadMBytesPerSec, totalMix.reads, result.WriteMBytesPerSec, totalMix.writes)
+	}
+	if result.OpenLatency > 0 {
+		logf("  Phases per file: open %v, stat %v, read %v, close %v\n",
+			result.OpenLatency, result.StatLatency, result.ReadLatency, result.CloseLatency)
+	}
+	if result.MissingReads > 0 {
+		logf("  Missing-file reads: %d\n", result.MissingReads)
+	}
+	if result.SyscallsPerOp > 0 {
+		logf("  Syscalls: %.2f per operation (%s)\n", result.SyscallsPerOp, result.Syscalls)
+	}
+	if result.Retries > 0 {
+		logf("  Retries: %d, taking %v (excluded from the duration)\n", result.Retries, result.RetryTime)
+	}
+	if writers != nil {
+		logf("  Background writers overwrote %d files (%.2f MB)\n", backgroundWrites, float64(backgroundBytes)/1024/1024)
+	}
+	if result.TimedOutReads > 0 {
+		errorf("  Warning: %d operations timed out after %s\n", result.TimedOutReads, config.ReadTimeout)
+	}
+	if len(result.WorkerLoads) > 0 {
+		logf("  Worker fairness (Jain's index over bytes): %.3f\n", result.Fairness)
+		for w, load := range result.WorkerLoads {
+			verbosef("    worker %d: %d reads, %d bytes\n", w, load.Reads, load.Bytes)
+		}
+	}
+	if result.HitRatio != nil {
+		logf("  Simulated LRU hit ratio (%d files): %.1f%%\n", config.CacheSizeFiles, *result.HitRatio*100)
+	}
+	logf("  Allocated %.2f MB in %d allocations, %d GC cycles, %v GC pause\n",
+		float64(result.AllocBytes)/1024/1024, result.Mallocs, result.NumGC, result.GCPauseTime)
+	return result, true
+}
+
+// printSummary prints the results table, one block per target directory
+// when more than one was benchmarked
+func printSummary(results BenchmarkResults) {
+	fmt.Println("\nSummary:")
+	for _, dir := range results.Config.TargetDirectory {
+		if len(results.Config.TargetDirectory) > 1 {
+			fmt.Printf("\nDirectory: %s\n", dir)
+		}
+		fmt.Println("Pattern               | Duration  | ± (ms)   | MB/s    | Files/s | p99 (ms)")
+		fmt.Println("----------------------|-----------|----------|---------|---------|---------")
+		for _, result := range results.Results {
+			if result.Directory != dir {
+				continue
+			}
+			if result.Error != "" {
+				fmt.Printf("%-20s | %9s | %8s | %7s | %7s | %8s\n", result.Pattern, "FAILED", "-", "-", "-", "-")
+				continue
+			}
+			fmt.Printf("%-20s | %9.3fs | %8.3f | %7.2f | %7.2f | %8.3f\n",
+				result.Pattern,
+				result.Duration.Seconds(),
+				result.StdDevMs,
+				result.MBytesPerSec,
+				result.ReadPerSec,
+				float64(result.P99)/float64(time.Millisecond))
+		}
+	}
+}
+
+// chartWidth is the length in characters of the fastest pattern's bar
+const chartWidth = 40
+
+// printChart draws each result's MB/s as a bar scaled to the fastest
+// result in its directory, in eighths of a character
+func printChart(results BenchmarkResults) {
+	eighths := []rune(" ▏▎▍▌▋▊▉")
+	fmt.Println("\nMB/s relative to the fastest:")
+	for _, dir := range results.Config.TargetDirectory {
+		if len(results.Config.TargetDirectory) > 1 {
+			fmt.Printf("\nDirectory: %s\n", dir)
+		}
+		var fastest float64
+		for _, result := range results.Results {
+			if result.Directory == dir && result.Error == "" {
+				fastest = max(fastest, result.MBytesPerSec)
+			}
+		}
+		for _, result := range results.Results {
+			if result.Directory != dir || result.Error != "" {
+				continue
+			}
+			label := result.Pattern
+			if len(results.Config.SweepWorkers) > 0 {
+				label += fmt.Sprintf(" x%d", result.Concurrency)
+			}
+			if result.Run > 0 {
+				label += fmt.Sprintf(" #%d", result.Run)
+			}
+			var units int
+			if fastest > 0 {
+				units = int(math.Round(result.MBytesPerSec / fastest * chartWidth * 8))
+			}
+			bar := strings.Repeat("█", units/8)
+			if units%8 > 0 {
+				bar += string(eighths[units%8])
+			}
+			fmt.Printf("%-22s %-*s %9.2f\n", label, chartWidth, bar, result.MBytesPerSec)
+		}
+	}
+}
+
+// aggregateRuns groups results by directory, pattern and concurrency in
+// first-seen order and averages the successful runs of each
+func aggregateRuns(results []BenchmarkResult) []RunAggregate {
+	type key struct {
+		dir, pattern string
+		workers      int
+	}
+	var order []key
+	groups := make(map[key][]BenchmarkResult)
+	for _, result := range results {
+		if result.Error != "" {
+			continue
+		}
+		k := key{result.Directory, result.Pattern, result.Concurrency}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], result)
+	}
+
+	aggregates := make([]RunAggregate, 0, len(order))
+	for _, k := range order {
+		mbps := make([]float64, len(groups[k]))
+		rps := make([]float64, len(groups[k]))
+		for i, result := range groups[k] {
+			mbps[i] = result.MBytesPerSec
+			rps[i] = result.ReadPerSec
+		}
+		agg := RunAggregate{Pattern: k.pattern, Directory: k.dir, Concurrency: k.workers, Runs: len(mbps)}
+		agg.MBytesPerSec, agg.MBytesPerSecStdDev = meanStdDev(mbps)
+		agg.ReadPerSec, agg.ReadPerSecStdDev = meanStdDev(rps)
+		aggregates = append(aggregates, agg)
+	}
+	return aggregates
+}
+
+// meanStdDev returns the mean and sample standard deviation of values
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)-1))
+}
+
+// printRunSummary prints each pattern's throughput averaged over the runs
+func printRunSummary(results BenchmarkResults) {
+	fmt.Printf("\nSummary over %d runs:\n", results.Config.Runs)
+	for _, dir := range results.Config.TargetDirectory {
+		if len(results.Config.TargetDirectory) > 1 {
+			fmt.Printf("\nDirectory: %s\n", dir)
+		}
+		fmt.Println("Pattern               | Runs | MB/s    | ± MB/s  | Files/s  | ± Files/s")
+		fmt.Println("----------------------|------|---------|---------|----------|----------")
+		for _, agg := range results.Aggregates {
+			if agg.Directory != dir {
+				continue
+			}
+			pattern := agg.Pattern
+			if len(results.Config.SweepWorkers) > 0 {
+				pattern = fmt.Sprintf("%s (%dw)", agg.Pattern, agg.Concurrency)
+			}
+			fmt.Printf("%-21s | %4d | %7.2f | %7.2f | %8.2f | %8.2f\n",
+				pattern, agg.Runs, agg.MBytesPerSec, agg.MBytesPerSecStdDev, agg.ReadPerSec, agg.ReadPerSecStdDev)
+		}
+	}
+}
+
+// printSweepSummary prints one row per pattern with its MB/s at every swept
+// concurrency level
+func printSweepSummary(results BenchmarkResults) {
+	levels := results.Config.SweepWorkers
+	fmt.Println("\nSweep summary (MB/s by workers):")
+	for _, dir := range results.Config.TargetDirectory {
+		if len(results.Config.TargetDirectory) > 1 {
+			fmt.Printf("\nDirectory: %s\n", dir)
+		}
+		header := fmt.Sprintf("%-22s", "Pattern")
+		rule := strings.Repeat("-", 22)
+		for _, w := range levels {
+			header += fmt.Sprintf("| %8d ", w)
+			rule += "|----------"
+		}
+		fmt.Println(strings.TrimRight(header, " "))
+		fmt.Println(rule)
+
+		// Results arrive grouped by pattern in level order
+		var order []string
+		byPattern := make(map[string][]BenchmarkResult)
+		for _, result := range results.Results {
+			if result.Directory != dir {
+				continue
+			}
+			if _, ok := byPattern[result.Pattern]; !ok {
+				order = append(order, result.Pattern)
+			}
+			byPattern[result.Pattern] = append(byPattern[result.Pattern], result)
+		}
+		for _, pattern := range order {
+			row := fmt.Sprintf("%-22s", pattern)
+			for _, w := range levels {
+				cell := "-"
+				for _, result := range byPattern[pattern] {
+					if result.Concurrency != w {
+						continue
+					}
+					if result.Error != "" {
+						cell = "FAILED"
+					} else {
+						cell = fmt.Sprintf("%.2f", result.MBytesPerSec)
+					}
+				}
+				row += fmt.Sprintf("| %8s ", cell)
+			}
+			fmt.Println(strings.TrimRight(row, " "))
+		}
+	}
+}
+
+// fileSizes picks the size in bytes of every file according to the
+// configured distribution
+func fileSizes(config BenchmarkConfig, rng *rand.Rand) []int64 {
+	sizes := make([]int64, config.NumFiles)
+	for i := range sizes {
+		switch config.SizeDistribution {
+		case "uniform":
+			minBytes := int64(config.MinFileSizeKB) * 1024
+			maxBytes := int64(config.MaxFileSizeKB) * 1024
+			sizes[i] = minBytes + rng.Int63n(maxBytes-minBytes+1)
+		case "lognormal":
+			// Choose mu so the distribution's mean is FileSizeKB
+			mean := float64(config.FileSizeKB) * 1024
+			mu := math.Log(mean) - config.SizeSigma*config.SizeSigma/2
+			sizes[i] = int64(math.Exp(mu + config.SizeSigma*rng.NormFloat64()))
+			if sizes[i] < 1 {
+				sizes[i] = 1
+			}
+		default:
+			sizes[i] = int64(config.FileSizeKB) * 1024
+		}
+	}
+	return sizes
+}
+
+func datasetStats(sizes []int64) DatasetStats {
+	if len(sizes) == 0 {
+		return DatasetStats{}
+	}
+	stats := DatasetStats{MinFileSize: sizes[0], MaxFileSize: sizes[0]}
+	for _, size := range sizes {
+		stats.TotalBytes += size
+		if size < stats.MinFileSize {
+			stats.MinFileSize = size
+		}
+		if size > stats.MaxFileSize {
+			stats.MaxFileSize = size
+		}
+	}
+	stats.AvgFileSize = stats.TotalBytes / int64(len(sizes))
+	return stats
+}
+
+// testFilePath returns where file i lives. With DirDepth the files fill
+// leaf directories FilesPerDir at a time, and the leaf number is spelled out
+// in base FilesPerDir across the levels, the top level taking any overflow.
+func testFilePath(dir string, i int, config BenchmarkConfig) string {
+	name := fmt.Sprintf("test_file_%04d.dat", i)
+	if config.DirDepth <= 0 {
+		return filepath.Join(dir, name)
+	}
+
+	parts := make([]string, config.DirDepth+2)
+	parts[0] = dir
+	parts[len(parts)-1] = name
+	leaf := i / config.FilesPerDir
+	for level := config.DirDepth; level >= 1; level-- {
+		digit := leaf
+		if level > 1 {
+			digit = leaf % config.FilesPerDir
+			leaf /= config.FilesPerDir
+		}
+		parts[level] = fmt.Sprintf("dir_%03d", digit)
+	}
+	return filepath.Join(parts...)
+}
+
+// countTestFiles counts the generated files anywhere under dir
+func countTestFiles(dir string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if matched, _ := filepath.Match("test_file_*.dat", d.Name()); matched && !d.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// loadExistingFiles builds the file list from a previous run's dataset,
+// checking that the file count and every size match without reading data
+func loadExistingFiles(dir string, sizes []int64, config BenchmarkConfig) ([]FileInfo, error) {
+	count, err := countTestFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if count != len(sizes) {
+		return nil, fmt.Errorf("found %d test files, expected %d", count, len(sizes))
+	}
+
+	files := make([]FileInfo, len(sizes))
+	for i, size := range sizes {
+		path := testFilePath(dir, i, config)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() != size {
+			return nil, fmt.Errorf("%s is %d bytes, expected %d", path, info.Size(), size)
+		}
+		files[i] = FileInfo{Path: path, Size: size}
+	}
+	return files, nil
+}
+
+// createTestFiles writes the dataset with content drawn from rng, so the
+// same seed regenerates the same bytes
+func createTestFiles(dir string, sizes []int64, config BenchmarkConfig, rng *rand.Rand) ([]FileInfo, error) {
+	files := make([]FileInfo, len(sizes))
+
+	// Data is streamed through one reusable chunk so memory use stays
+	// constant no matter how large the files are
+	chunk := make([]byte, writeChunkSize)
+	mode, _ := parseFileMode(config.FileMode)
+	fill := func(buf []byte) { fillContent(rng, buf, config.Compressibility) }
+	if config.ZeroContent {
+		// The pattern is written once and every chunk reuses it
+		for i := range chunk {
+			chunk[i] = byte(i)
+		}
+		fill = nil
+	}
+
+	for i, sizeBytes := range sizes {
+		filename := testFilePath(dir, i, config)
+		if config.DirDepth > 0 {
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory for %s: %w", filename, err)
+			}
+		}
+
+		var checksum uint32
+		var err error
+		if config.Sparse {
+			checksum, err = createSparseFile(filename, sizeBytes, mode, config.Verify)
+		} else {
+			checksum, err = writeTestFile(filename, sizeBytes, chunk, mode, fill, config.Backend == "gzip")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write file %s: %w", filename, err)
+		}
+
+		files[i] = FileInfo{
+			Path:     filename,
+			Size:     sizeBytes,
+			Checksum: checksum,
+		}
+	}
+
+	return files, nil
+}
+
+const writeChunkSize = 1 << 20
+
+// compressSegment is the granularity at which random and repeated bytes are
+// mixed, small enough that compressors see both within their window
+const compressSegment = 4096
+
+// writeTestFile fills path with size bytes of generated content, gzipped if
+// requested, and returns the CRC32 of the uncompressed content. fill
+// regenerates chunk before each write, with a nil fill chunk is written as
+// it is. The file is chmodded to mode explicitly so the umask doesn't strip
+// any bits.
+func writeTestFile(path string, size int64, chunk []byte, mode os.FileMode, fill func([]byte), gzipped bool) (uint32, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return 0, err
+	}
+
+	var w io.Writer = f
+	var zw *gzip.Writer
+	if gzipped {
+		zw = gzip.NewWriter(f)
+		w = zw
+	}
+
+	var checksum uint32
+	for remaining := size; remaining > 0; {
+		n := int64(len(chunk))
+		if remaining < n {
+			n = remaining
+		}
+		if fill != nil {
+			fill(chunk[:n])
+		}
+		checksum = crc32.Update(checksum, crc32.IEEETable, chunk[:n])
+		if _, err := w.Write(chunk[:n]); err != nil {
+			f.Close()
+			return 0, err
+		}
+		remaining -= n
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			f.Close()
+			return 0, err
+		}
+	}
+	return checksum, f.Close()
+}
+
+// createSparseFile makes path a hole of size bytes. The CRC32 of its zeros
+// is only computed when checksum is set, skipping it keeps setup of huge
+// datasets instant.
+func createSparseFile(path string, size int64, mode os.FileMode, checksum bool) (uint32, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if !checksum {
+		return 0, nil
+	}
+
+	var crc uint32
+	zeros := make([]byte, min(size, writeChunkSize))
+	for remaining := size; remaining > 0; remaining -= int64(len(zeros)) {
+		crc = crc32.Update(crc, crc32.IEEETable, zeros[:min(remaining, int64(len(zeros)))])
+	}
+	return crc, nil
+}
+
+// parseFileMode parses an octal mode string such as "0644" or "4755" into
+// an os.FileMode, mapping the setuid, setgid and sticky bits
+func parseFileMode(s string) (os.FileMode, error) {
+	bits, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || bits > 07777 {
+		return 0, fmt.Errorf("fileMode must be an octal mode between 0000 and 7777, got %q", s)
+	}
+	mode := os.FileMode(bits & 0777)
+	if bits&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+// fillContent fills buf with random bytes from rng, then zeroes the
+// trailing compressibility fraction of every segment
+func fillContent(rng *rand.Rand, buf []byte, compressibility float64) {
+	rng.Read(buf)
+	if compressibility <= 0 {
+		return
+	}
+	repeated := int(compressibility * compressSegment)
+	for start := 0; start < len(buf); start += compressSegment {
+		end := start + compressSegment
+		if end > len(buf) {
+			end = len(buf)
+		}
+		from := end - repeated
+		if from < start {
+			from = start
+		}
+		clear(buf[from:end])
+	}
+}
+
+// pickMissing chooses which ratio of n files to delete
+func pickMissing(n int, ratio float64, rng *rand.Rand) []int {
+	count := int(float64(n) * ratio)
+	return rng.Perm(n)[:count]
+}
+
+// removeFiles deletes the files at the given indices
+func removeFiles(files []FileInfo, indices []int) error {
+	for _, i := range indices {
+		if err := os.Remove(files[i].Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// checksumFiles records the current contents' checksum of every file
+func checksumFiles(files []FileInfo) error {
+	for i := range files {
+		data, err := os.ReadFile(files[i].Path)
+		if err != nil {
+			return err
+		}
+		files[i].Checksum = crc32.ChecksumIEEE(data)
+	}
+	return nil
+}
+
+func verifyChecksum(file FileInfo, checksum uint32) error {
+	if checksum != file.Checksum {
+		return fmt.Errorf("checksum mismatch in %s: read %08x, wrote %08x", file.Path, checksum, file.Checksum)
+	}
+	return nil
+}
+
+// buildAccessOrder generates the pattern's order for one iteration, cut down
+// to SampleSize and expanded by ReadsPerFile
+func buildAccessOrder(files []FileInfo, patternID int, rng *rand.Rand, config BenchmarkConfig) []int {
+	order := createAccessPattern(files, patternID, rng, config)
+	order = sampleAccessOrder(order, config.SampleSize, rng)
+	return repeatAccessOrder(order, config.ReadsPerFile, config.RepeatMode == "interleaved")
+}
+
+// sampleAccessOrder returns n consecutive entries of order starting at a
+// random offset and wrapping around, so ordered patterns keep their shape
+// while successive iterations cover different parts of the file set
+func sampleAccessOrder(order []int, n int, rng *rand.Rand) []int {
+	if n <= 0 || n >= len(order) {
+		return order
+	}
+	start := rng.Intn(len(order))
+	sample := make([]int, 0, n)
+	sample = append(sample, order[start:min(start+n, len(order))]...)
+	return append(sample, order[:n-len(sample)]...)
+}
+
+// repeatAccessOrder reads every entry of order k times, either back to back
+// or by replaying the whole order k times
+func repeatAccessOrder(order []int, k int, interleaved bool) []int {
+	if k <= 1 {
+		return order
+	}
+	repeated := make([]int, 0, len(order)*k)
+	if interleaved {
+		for r := 0; r < k; r++ {
+			repeated = append(repeated, order...)
+		}
+		return repeated
+	}
+	for _, idx := range order {
+		for r := 0; r < k; r++ {
+			repeated = append(repeated, idx)
+		}
+	}
+	return repeated
+}
+
+// fileOp performs one timed operation against a file and returns the number
+// of bytes transferred
+type fileOp func(file FileInfo) (int64, error)
+
+// newWholeReadOp returns an op that reads each file whole the way
+// os.ReadFile does
+func newWholeReadOp(verify bool, calls *syscallCounter) fileOp {
+	return func(file FileInfo) (int64, error) {
+		data, err := readCounted(file.Path, calls)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read file %s: %w", file.Path, err)
+		}
+		if verify {
+			return int64(len(data)), verifyChecksum(file, crc32.ChecksumIEEE(data))
+		}
+		return int64(len(data)), nil
+	}
+}
+
+// newBufioReadOp returns an op that streams each file through a
+// bufio.Reader of bufSize bytes, consuming it a buffer at a time. Copying
+// into io.Discard would skip the buffer since *os.File is a WriterTo.
+func newBufioReadOp(bufSize int, verify bool, calls *syscallCounter) fileOp {
+	return func(file FileInfo) (int64, error) {
+		f, err := openCounted(file.Path, os.O_RDONLY, 0, calls)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open file %s: %w", file.Path, err)
+		}
+		defer f.Close()
+
+		br := bufio.NewReaderSize(f, bufSize)
+		var total int64
+		var checksum uint32
+		for {
+			buf, err := br.Peek(bufSize)
+			if len(buf) > 0 {
+				if verify {
+					checksum = crc32.Update(checksum, crc32.IEEETable, buf)
+				}
+				br.Discard(len(buf))
+				total += int64(len(buf))
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return total, fmt.Errorf("failed to read file %s: %w", file.Path, err)
+			}
+		}
+		if verify {
+			return total, verifyChecksum(file, checksum)
+		}
+		return total, nil
+	}
+}
+
+// newReuseBuffers allocates one buffer per worker at the largest file size
+// for newReuseReadOp
+func newReuseBuffers(files []FileInfo, workers int) chan []byte {
+	var maxSize int64
+	for _, file := range files {
+		maxSize = max(maxSize, file.Size)
+	}
+	buffers := make(chan []byte, workers)
+	for i := 0; i < workers; i++ {
+		buffers <- make([]byte, maxSize)
+	}
+	return buffers
+}
+
+// newReuseReadOp returns an op that reads each file whole into one of the
+// preallocated buffers, so reads don't allocate the way os.ReadFile does
+func newReuseReadOp(buffers chan []byte, verify bool, calls *syscallCounter) fileOp {
+	return func(file FileInfo) (int64, error) {
+		f, err := openCounted(file.Path, os.O_RDONLY, 0, calls)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open file %s: %w", file.Path, err)
+		}
+		defer f.Close()
+
+		buf := <-buffers
+		defer func() { buffers <- buf }()
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return int64(n), fmt.Errorf("failed to read file %s: %w", file.Path, err)
+		}
+		if verify {
+			return int64(n), verifyChecksum(file, crc32.ChecksumIEEE(buf[:n]))
+		}
+		return int64(n), nil
+	}
+}
+
+// newGzipReadOp returns an op that decompresses the whole file, returning
+// the uncompressed size
+func newGzipReadOp(verify bool, calls *syscallCounter) fileOp {
+	return func(file FileInfo) (int64, error) {
+		return gzipRead(file, verify, calls)
+	}
+}
+
+func gzipRead(file FileInfo, verify bool, calls *syscallCounter) (int64, error) {
+	f, err := openCounted(file.Path, os.O_RDONLY, 0, calls)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file %s: %w", file.Path, err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decompress file %s: %w", file.Path, err)
+	}
+	hash := crc32.NewIEEE()
+	var dst io.Writer = io.Discard
+	if verify {
+		dst = hash
+	}
+	n, err := io.Copy(dst, zr)
+	if err != nil {
+		return n, fmt.Errorf("failed to decompress file %s: %w", file.Path, err)
+	}
+	if verify {
+		return n, verifyChecksum(file, hash.Sum32())
+	}
+	return n, nil
+}
+
+// adviseSequential reports whether patternID's reads get the
+// POSIX_FADV_SEQUENTIAL hint under config
+func adviseSequential(patternID int, config BenchmarkConfig) bool {
+	return config.FadviseSequential && (patternID == PatternSequential || patternID == PatternStrided)
+}
+
+// newAdvisedReadOp reads whole files like newWholeReadOp, but through a
+// descriptor marked POSIX_FADV_SEQUENTIAL first. The wider readahead only
+// applies to reads made through that descriptor.
+func newAdvisedReadOp(verify bool, calls *syscallCounter) fileOp {
+	return func(file FileInfo) (int64, error) {
+		f, err := openCounted(file.Path, os.O_RDONLY, 0, calls)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open file %s: %w", file.Path, err)
+		}
+		defer f.Close()
+		if err := f.fadvise(fadvSequential); err != nil {
+			return 0, fmt.Errorf("fadvise SEQUENTIAL on %s: %w", file.Path, err)
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat file %s: %w", file.Path, err)
+		}
+		data := make([]byte, info.Size())
+		n, err := io.ReadFull(f, data)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return int64(n), fmt.Errorf("failed to read file %s: %w", file.Path, err)
+		}
+		if verify {
+			return int64(n), verifyChecksum(file, crc32.ChecksumIEEE(data[:n]))
+		}
+		return int64(n), nil
+	}
+}
+
+// IterationResult is what one pass of runBenchmark measured
+type IterationResult struct {
+	// Duration excludes think time and idle gaps, WallTime is the elapsed
+	// time including them
+	Duration  time.Duration
+	WallTime  time.Duration
+	BytesRead int64
+
+	// Ops counts successful operations and IOs every read or write call,
+	// more than one per file with block reads. Each operation's latency is
+	// in Latencies, or in Histogram when streaming.
+	Ops       int64
+	IOs       int64
+	Latencies []time.Duration
+	Histogram *latencyHistogram
+
+	// ErrorCount is the number of reads that found their file missing,
+	// Timeouts the number abandoned after ReadTimeout
+	ErrorCount int64
+	Timeouts   int64
+
+	// Retries counts operations retried after a failure and RetryTime the
+	// time the failed attempts and backoffs took, which Duration excludes
+	Retries   int64
+	RetryTime time.Duration
+
+	// Throughput is the bytes completed per window, nil unless
+	// ThroughputWindowMs is set
+	Throughput []int64
+
+	// CacheHits counts simulated LRU hits, Mix is only set for PatternMixed,
+	// Phases only with PhaseTiming and Syscalls only with CountSyscalls
+	CacheHits int64
+	Mix       *mixStats
+	Phases    *phaseStats
+	Syscalls  *syscallCounter
+
+	// Order is the access order the iteration was run from
+	Order accessSeq
+
+	// Workers has the reads and bytes each worker completed, only set
+	// when running concurrently
+	Workers []WorkerLoad
+}
+
+// newIterationResult starts the result of running order, with room for
+// its latencies unless streaming
+func newIterationResult(order accessSeq, streaming bool) IterationResult {
+	if streaming {
+		return IterationResult{Order: order, Histogram: &latencyHistogram{}}
+	}
+	return IterationResult{Order: order, Latencies: make([]time.Duration, 0, order.len())}
+}
+
+// record counts a successful operation that took latency
+func (r *IterationResult) record(latency time.Duration) {
+	r.Ops++
+	if r.Histogram != nil {
+		r.Histogram.record(latency)
+		return
+	}
+	r.Latencies = append(r.Latencies, latency)
+}
+
+// WorkerLoad is the work one concurrent worker completed
+type WorkerLoad struct {
+	Reads int64 `json:"reads"`
+	Bytes int64 `json:"bytes"`
+}
+
+// runResources are shared by every iteration of one pattern run
+type runResources struct {
+	// buffers are the reuse backend's, nil for the other backends
+	buffers chan []byte
+
+	// slots bound the operations holding a file open to MaxOpenFiles,
+	// timed out ones still running in the background included
+	slots chan struct{}
+}
+
+func newRunResources(files []FileInfo, config BenchmarkConfig) runResources {
+	shared := runResources{slots: make(chan struct{}, config.MaxOpenFiles)}
+	if config.Backend == "reuse" {
+		shared.buffers = newReuseBuffers(files, config.Concurrency)
+	}
+	return shared
+}
+
+// releaseSlot gives back the slot an op was issued with, unless the op timed
+// out: its abandoned call keeps the slot until it returns
+func releaseSlot(slots chan struct{}, err error) {
+	if !errors.Is(err, errReadTimeout) {
+		<-slots
+	}
 }
 
-const (
-	PatternSequential     = 1
-	PatternReverseSeq     = 2
-	PatternRandom         = 3
-	PatternZipfian        = 4
-	PatternLocalityBased  = 5
-	PatternRepeatedAccess = 6
-)
+// runBenchmark runs one iteration of patternID
+func runBenchmark(files []FileInfo, patternID int, rng *rand.Rand, config BenchmarkConfig, shared runResources) (IterationResult, error) {
+	accessOrder := buildAccessSeq(files, patternID, rng, config)
 
-func main() {
-	configPath := flag.String("config", "", "Path to configuration JSON file")
-	outputPath := flag.String("output", "benchmark_results.json", "Path to output JSON results")
-	numFiles := flag.Int("files", 100, "Number of files to create")
-	fileSizeKB := flag.Int("size", 1024, "Size of each file in KB")
-	targetDir := flag.String("dir", "benchmark_files", "Directory to create files in")
-	iterations := flag.Int("iter", 10, "Number of iterations for each benchmark")
-	flag.Parse()
+	var calls *syscallCounter
+	if config.CountSyscalls {
+		calls = &syscallCounter{}
+	}
+	op := newWholeReadOp(config.Verify, calls)
+	if config.Backend == "mmap" {
+		op = newMmapReadOp(config.Verify, calls)
+	}
+	if config.Backend == "bufio" {
+		op = newBufioReadOp(config.BufSizeKB*1024, config.Verify, calls)
+	}
+	if config.Backend == "reuse" {
+		op = newReuseReadOp(shared.buffers, config.Verify, calls)
+	}
+	if config.Backend == "gzip" {
+		op = newGzipReadOp(config.Verify, calls)
+	}
+	advise := adviseSequential(patternID, config)
+	if advise {
+		op = newAdvisedReadOp(config.Verify, calls)
+	}
+	if config.ReadFraction > 0 {
+		op = newPrefixReadOp(config.ReadFraction, calls)
+	}
+	var extraReads *int64
+	if config.Backend == "preadv" {
+		op, extraReads = newPreadvReadOp(int64(config.IovecKB)*1024, config.Verify, calls)
+	}
+	if config.BlockSizeKB > 0 {
+		op, extraReads = newBlockReadOp(rng, int64(config.BlockSizeKB)*1024, config.BlockOffsets == "random", int64(config.BlockAlign), config.BlockMisalign, config.Verify, advise, calls)
+	}
+	var phases *phaseStats
+	if config.PhaseTiming {
+		op, phases = newPhasedReadOp(config.Verify, calls)
+	}
+	if isWritePattern(patternID) {
+		var cleanup func()
+		op, cleanup = newWriteOp(files, rng, config.WriteNewFiles, config.Fsync, config.ODirect, calls)
+		defer cleanup()
+	}
+	var mix *mixStats
+	if patternID == PatternMixed {
+		write, cleanup := newWriteOp(files, rng, false, config.Fsync, config.ODirect, calls)
+		defer cleanup()
+		op, mix = newMixedOp(op, write, rng, config.WriteRatio)
+	}
 
-	var config BenchmarkConfig
+	var retries *retryStats
+	if config.MaxRetries > 0 {
+		backoff, _ := time.ParseDuration(config.RetryBackoff)
+		op, retries = newRetryOp(op, config.MaxRetries, backoff)
+	}
+	if timeout := config.readTimeout(); timeout > 0 {
+		op = withTimeout(op, timeout, shared.slots)
+	}
 
-	if *configPath != "" {
-		data, err := os.ReadFile(*configPath)
+	windows := newWindowRecorder(time.Duration(config.ThroughputWindowMs) * time.Millisecond)
+	cache := newLRUSim(config.CacheSizeFiles)
+	budget := config.runDuration()
+	pace := config.pacing()
+	if accessOrder.len() == 0 {
+		return IterationResult{Throughput: windows.series(), Mix: mix, Phases: phases}, nil
+	}
+
+	if config.Backend == "iouring" && !isWritePattern(patternID) {
+		result, err := runIOURing(files, accessOrder, config.Streaming, config.QueueDepth, config.Verify, windows, cache, budget, calls)
+		result.Syscalls = calls
+		return result, err
+	}
+	if config.Concurrency > 1 {
+		result, err := runConcurrent(files, accessOrder, config.Streaming, config.Concurrency, shared.slots, op, windows, cache, budget, pace)
+		result.Mix = mix
+		result.Phases = phases
+		result.Syscalls = calls
+		result.Order = accessOrder
+		result.IOs = countIOs(result, extraReads)
+		// Like think time, retries happen in parallel across the workers
+		result.Retries, result.RetryTime = retries.totals()
+		result.Duration -= result.RetryTime / time.Duration(config.Concurrency)
+		return result, err
+	}
+
+	result := newIterationResult(accessOrder, config.Streaming)
+	result.Mix = mix
+	result.Phases = phases
+	result.Syscalls = calls
+
+	startTime := time.Now()
+	windows.begin(startTime)
+
+	var paused time.Duration
+	for i := 0; keepIssuing(i, accessOrder.len(), startTime, budget); i++ {
+		if i > 0 && pace.burst > 0 && i%pace.burst == 0 {
+			paused += pause(pace.idle)
+		}
+		if i > 0 && pace.think > 0 {
+			paused += pause(pace.think)
+		}
+		idx := accessOrder.at(i % accessOrder.len())
+		cache.access(idx)
+		shared.slots <- struct{}{}
+		opStart := time.Now()
+		n, err := op(files[idx])
+		releaseSlot(shared.slots, err)
+		if errors.Is(err, fs.ErrNotExist) {
+			result.ErrorCount++
+			continue
+		}
+		if errors.Is(err, errReadTimeout) {
+			result.Timeouts++
+			continue
+		}
 		if err != nil {
-			fmt.Printf("Error reading config file: %v\n", err)
-			os.Exit(1)
+			return IterationResult{}, err
 		}
-		if err := json.Unmarshal(data, &config); err != nil {
-			fmt.Printf("Error parsing config file: %v\n", err)
-			os.Exit(1)
+		latency := time.Since(opStart)
+		result.record(latency)
+		windows.add(n)
+		result.BytesRead += n
+		verbosef("    %s: %d bytes in %s\n", files[idx].Path, n, latency)
+	}
+
+	result.WallTime = time.Since(startTime)
+	result.Retries, result.RetryTime = retries.totals()
+	result.Duration = result.WallTime - paused - result.RetryTime
+	result.IOs = countIOs(result, extraReads)
+	result.Throughput = windows.series()
+	result.CacheHits = cache.hitCount()
+	return result, nil
+}
+
+// retryStats counts the retries of a newRetryOp and the time they cost
+type retryStats struct {
+	mu      sync.Mutex
+	retries int64
+	lost    time.Duration
+}
+
+func (s *retryStats) totals() (int64, time.Duration) {
+	if s == nil {
+		return 0, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.retries, s.lost
+}
+
+// newRetryOp returns an op that retries op up to maxRetries times with
+// doubling backoff. Missing files and timeouts aren't retried, they are
+// counted on their own.
+func newRetryOp(op fileOp, maxRetries int, backoff time.Duration) (fileOp, *retryStats) {
+	stats := &retryStats{}
+	retry := func(file FileInfo) (int64, error) {
+		wait := backoff
+		var lost time.Duration
+		for attempt := 0; ; attempt++ {
+			start := time.Now()
+			n, err := op(file)
+			if err == nil || attempt == maxRetries || errors.Is(err, fs.ErrNotExist) || errors.Is(err, errReadTimeout) {
+				if attempt > 0 {
+					stats.mu.Lock()
+					stats.retries += int64(attempt)
+					stats.lost += lost
+					stats.mu.Unlock()
+				}
+				return n, err
+			}
+			verbosef("    retrying %s after %v: %v\n", file.Path, wait, err)
+			lost += time.Since(start) + pause(wait)
+			wait *= 2
 		}
-	} else {
-		config = BenchmarkConfig{
-			NumFiles:        *numFiles,
-			FileSizeKB:      *fileSizeKB,
-			ReadPatterns:    []int{PatternSequential, PatternReverseSeq, PatternRandom, PatternZipfian, PatternLocalityBased, PatternRepeatedAccess},
-			TargetDirectory: *targetDir,
-			Iterations:      *iterations,
+	}
+	return retry, stats
+}
+
+var errReadTimeout = errors.New("timed out")
+
+// withTimeout abandons op once it has taken longer than timeout. A blocked
+// read can't be interrupted, so the abandoned call carries on in the
+// background, keeping its file, any buffer and the slot it was issued with
+// until it returns.
+func withTimeout(op fileOp, timeout time.Duration, slots chan struct{}) fileOp {
+	type outcome struct {
+		n   int64
+		err error
+	}
+	return func(file FileInfo) (int64, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		done := make(chan outcome, 1)
+		go func() {
+			n, err := op(file)
+			done <- outcome{n, err}
+		}()
+		select {
+		case o := <-done:
+			return o.n, o.err
+		case <-ctx.Done():
+			go func() {
+				<-done
+				<-slots
+			}()
+			return 0, fmt.Errorf("%s: %w after %v", file.Path, errReadTimeout, timeout)
 		}
 	}
+}
 
-	results := BenchmarkResults{
-		Config:  config,
-		Results: []BenchmarkResult{},
+// countIOs is one I/O per completed operation plus any extra block reads
+func countIOs(result IterationResult, extraReads *int64) int64 {
+	ios := result.Ops
+	if extraReads != nil {
+		ios += atomic.LoadInt64(extraReads)
+	}
+	return ios
+}
+
+// pause sleeps for d and returns how long it actually slept
+func pause(d time.Duration) time.Duration {
+	start := time.Now()
+	time.Sleep(d)
+	return time.Since(start)
+}
+
+// keepIssuing reports whether operation i should be issued: one pass over
+// the access order without a budget, otherwise until the budget runs out
+func keepIssuing(i, n int, start time.Time, budget time.Duration) bool {
+	if budget > 0 {
+		return time.Since(start) < budget
 	}
+	return i < n
+}
 
-	hostname, _ := os.Hostname()
-	results.System.Hostname = hostname
-	results.System.Timestamp = time.Now().Format(time.RFC3339)
+// windowRecorder accumulates bytes completed per fixed time window. A nil
+// recorder ignores every call so callers don't need to check.
+type windowRecorder struct {
+	mu     sync.Mutex
+	start  time.Time
+	window time.Duration
+	bytes  []int64
+}
 
-	err := os.MkdirAll(config.TargetDirectory, 0755)
-	if err != nil {
-		fmt.Printf("Error creating target directory: %v\n", err)
-		os.Exit(1)
+func newWindowRecorder(window time.Duration) *windowRecorder {
+	if window <= 0 {
+		return nil
 	}
+	return &windowRecorder{window: window}
+}
 
-	fmt.Printf("Creating %d files of %d KB each in %s...\n", config.NumFiles, config.FileSizeKB, config.TargetDirectory)
-	files, err := createTestFiles(config.TargetDirectory, config.NumFiles, config.FileSizeKB*1024)
-	if err != nil {
-		fmt.Printf("Error creating test files: %v\n", err)
-		os.Exit(1)
+func (w *windowRecorder) begin(start time.Time) {
+	if w != nil {
+		w.start = start
+	}
+}
+
+func (w *windowRecorder) add(n int64) {
+	if w == nil {
+		return
+	}
+	idx := int(time.Since(w.start) / w.window)
+	w.mu.Lock()
+	for len(w.bytes) <= idx {
+		w.bytes = append(w.bytes, 0)
 	}
+	w.bytes[idx] += n
+	w.mu.Unlock()
+}
 
-	for _, patternID := range config.ReadPatterns {
-		patternName := getPatternName(patternID)
-		fmt.Printf("Running benchmark for %s pattern (%d iterations)...\n", patternName, config.Iterations)
+func (w *windowRecorder) series() []int64 {
+	if w == nil {
+		return nil
+	}
+	return w.bytes
+}
 
-		var totalDuration time.Duration
-		var totalBytes int64
+// lruSim tracks which files an LRU cache of a fixed number of files would
+// hold as the access order is issued. It doesn't affect the real reads.
+// Like windowRecorder, a nil sim ignores every call.
+type lruSim struct {
+	capacity int
+	order    *list.List
+	entries  map[int]*list.Element
+	hits     int64
+}
 
-		for i := 0; i < config.Iterations; i++ {
-			fmt.Printf("  Iteration %d/%d...\n", i+1, config.Iterations)
-			duration, bytesRead, err := runBenchmark(files, patternID)
-			if err != nil {
-				fmt.Printf("Error running benchmark: %v\n", err)
-				continue
+func newLRUSim(capacity int) *lruSim {
+	if capacity <= 0 {
+		return nil
+	}
+	return &lruSim{capacity: capacity, order: list.New(), entries: make(map[int]*list.Element)}
+}
+
+func (c *lruSim) access(idx int) {
+	if c == nil {
+		return
+	}
+	if e, ok := c.entries[idx]; ok {
+		c.hits++
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[idx] = c.order.PushFront(idx)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(int))
+	}
+}
+
+func (c *lruSim) hitCount() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.hits
+}
+
+// newBlockReadOp returns an op that opens each file once and reads it in
+// blockSize chunks with ReadAt, either front to back or at random offsets.
+// Either way it issues ceil(size/blockSize) reads per file. With verify the
+// sequential blocks are hashed as they're read. A non-zero align rounds each
+// offset down to a multiple of it, misalign then adds half of it. The
+// returned counter holds the reads issued beyond the first of each file.
+func newBlockReadOp(rng *rand.Rand, blockSize int64, randomOffsets bool, align int64, misalign, verify, advise bool, calls *syscallCounter) (fileOp, *int64) {
+	var mu sync.Mutex
+	offsetRng := rand.New(rand.NewSource(rng.Int63()))
+	var extraReads int64
+
+	op := func(file FileInfo) (int64, error) {
+		f, err := openCounted(file.Path, os.O_RDONLY, 0, calls)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open file %s: %w", file.Path, err)
+		}
+		defer f.Close()
+		if advise {
+			if err := f.fadvise(fadvSequential); err != nil {
+				return 0, fmt.Errorf("fadvise SEQUENTIAL on %s: %w", file.Path, err)
 			}
-			totalDuration += duration
-			totalBytes += bytesRead
 		}
 
-		avgDuration := totalDuration / time.Duration(config.Iterations)
-		avgBytes := totalBytes / int64(config.Iterations)
+		buf := make([]byte, blockSize)
+		blocks := (file.Size + blockSize - 1) / blockSize
+		if blocks > 1 {
+			atomic.AddInt64(&extraReads, blocks-1)
+		}
+		var total int64
+		var checksum uint32
+		for b := int64(0); b < blocks; b++ {
+			offset := b * blockSize
+			if randomOffsets && file.Size > blockSize {
+				mu.Lock()
+				offset = offsetRng.Int63n(file.Size - blockSize + 1)
+				mu.Unlock()
+			}
+			if align > 0 {
+				offset -= offset % align
+				if misalign {
+					offset += align / 2
+				}
+			}
+			n, err := f.ReadAt(buf, offset)
+			if err != nil && err != io.EOF {
+				return total, fmt.Errorf("failed to read file %s at offset %d: %w", file.Path, offset, err)
+			}
+			total += int64(n)
+			if verify {
+				checksum = crc32.Update(checksum, crc32.IEEETable, buf[:n])
+			}
+		}
+		if verify {
+			return total, verifyChecksum(file, checksum)
+		}
+		return total, nil
+	}
+	return op, &extraReads
+}
 
-		fileCount := len(files)
-		readPerSec := float64(fileCount) / avgDuration.Seconds()
-		mbytesPerSec := float64(avgBytes) / 1024 / 1024 / avgDuration.Seconds()
+// newPrefixReadOp returns an op that reads the first fraction of each file,
+// rounded up to a whole byte
+func newPrefixReadOp(fraction float64, calls *syscallCounter) fileOp {
+	return func(file FileInfo) (int64, error) {
+		f, err := openCounted(file.Path, os.O_RDONLY, 0, calls)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open file %s: %w", file.Path, err)
+		}
+		defer f.Close()
 
-		results.Results = append(results.Results, BenchmarkResult{
-			Pattern:      patternName,
-			Duration:     avgDuration,
-			FileCount:    fileCount,
-			BytesRead:    avgBytes,
-			ReadPerSec:   readPerSec,
-			MBytesPerSec: mbytesPerSec,
-		})
+		limit := int64(math.Ceil(float64(file.Size) * fraction))
+		buf := make([]byte, limit)
+		n, err := io.ReadFull(io.LimitReader(f, limit), buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return int64(n), fmt.Errorf("failed to read file %s: %w", file.Path, err)
+		}
+		return int64(n), nil
+	}
+}
 
-		fmt.Printf("  Result: %.2f MB/s, %.2f files/s\n", mbytesPerSec, readPerSec)
+// newWriteOp returns an op that writes random data of each file's size,
+// either over the existing file or into a fresh sibling file. The returned
+// cleanup removes any files created that way and is not timed. With fsync
+// each write includes syncing the file, with direct it goes through O_DIRECT.
+func newWriteOp(files []FileInfo, rng *rand.Rand, createNew, fsync, direct bool, calls *syscallCounter) (fileOp, func()) {
+	var maxSize int64
+	for _, file := range files {
+		if file.Size > maxSize {
+			maxSize = file.Size
+		}
+	}
+	data := make([]byte, maxSize)
+	if direct {
+		data = alignedBuffer(alignUp(maxSize, directIOAlign))
 	}
+	rng.Read(data)
 
-	fmt.Println("Cleaning up...")
-	cleanupFiles(files)
+	var mu sync.Mutex
+	var created []string
 
-	resultData, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		fmt.Printf("Error serializing results: %v\n", err)
-		os.Exit(1)
+	op := func(file FileInfo) (int64, error) {
+		path := file.Path
+		if createNew {
+			path = writeTargetPath(file)
+		}
+		if err := writeFile(path, data, file.Size, fsync, direct, calls); err != nil {
+			return 0, fmt.Errorf("failed to write file %s: %w", path, err)
+		}
+		if createNew {
+			mu.Lock()
+			created = append(created, path)
+			mu.Unlock()
+		}
+		return file.Size, nil
+	}
+
+	cleanup := func() {
+		for _, path := range created {
+			os.Remove(path)
+		}
 	}
+	return op, cleanup
+}
 
-	err = os.WriteFile(*outputPath, resultData, 0644)
+// writeFile writes the first size bytes of data to path. O_DIRECT writes
+// must cover whole aligned blocks, so they're padded and truncated back.
+func writeFile(path string, data []byte, size int64, fsync, direct bool, calls *syscallCounter) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	n := size
+	if direct {
+		flags |= directIOFlag
+		n = alignUp(size, directIOAlign)
+	}
+	f, err := openCounted(path, flags, 0644, calls)
 	if err != nil {
-		fmt.Printf("Error writing results to %s: %v\n", *outputPath, err)
-		os.Exit(1)
+		return err
+	}
+	if _, err := f.Write(data[:n]); err != nil {
+		f.Close()
+		return err
+	}
+	if n != size {
+		if err := f.Truncate(size); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if fsync {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
 	}
+	return f.Close()
+}
 
-	fmt.Printf("Benchmark complete. Results saved to %s\n", *outputPath)
+func alignUp(n, align int64) int64 {
+	return (n + align - 1) / align * align
+}
 
-	fmt.Println("\nSummary:")
-	fmt.Println("Pattern               | Duration  | MB/s    | Files/s")
-	fmt.Println("----------------------|-----------|---------|---------")
-	for _, result := range results.Results {
-		fmt.Printf("%-20s | %9.3fs | %7.2f | %7.2f\n",
-			result.Pattern,
-			result.Duration.Seconds(),
-			result.MBytesPerSec,
-			result.ReadPerSec)
+// alignedBuffer returns a buffer of size bytes starting on a directIOAlign
+// boundary
+func alignedBuffer(size int64) []byte {
+	buf := make([]byte, size+directIOAlign)
+	offset := int64(uintptr(unsafe.Pointer(&buf[0])) % directIOAlign)
+	if offset != 0 {
+		offset = directIOAlign - offset
 	}
+	return buf[offset : offset+size]
 }
 
-func createTestFiles(dir string, count, sizeBytes int) ([]FileInfo, error) {
-	files := make([]FileInfo, count)
+func writeTargetPath(file FileInfo) string {
+	return file.Path + ".new"
+}
 
-	for i := 0; i < count; i++ {
-		filename := filepath.Join(dir, fmt.Sprintf("test_file_%04d.dat", i))
+func isWritePattern(patternID int) bool {
+	return patternID == PatternWriteSequential || patternID == PatternWriteRandom
+}
 
-		data := make([]byte, sizeBytes)
-		rand.Read(data)
+// writesFiles reports whether a pattern modifies the dataset's files
+func writesFiles(patternID int) bool {
+	return isWritePattern(patternID) || patternID == PatternMixed
+}
 
-		err := os.WriteFile(filename, data, 0644)
-		if err != nil {
-			return nil, fmt.Errorf("failed to write file %s: %w", filename, err)
-		}
+// mixStats splits a Mixed pattern's operations into reads and writes
+type mixStats struct {
+	mu         sync.Mutex
+	reads      int64
+	writes     int64
+	readBytes  int64
+	writeBytes int64
+	readTime   time.Duration
+	writeTime  time.Duration
+}
 
-		files[i] = FileInfo{
-			Path:     filename,
-			Size:     int64(sizeBytes),
-			Contents: data,
-		}
+func (m *mixStats) record(write bool, n int64, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if write {
+		m.writes++
+		m.writeBytes += n
+		m.writeTime += d
+	} else {
+		m.reads++
+		m.readBytes += n
+		m.readTime += d
 	}
+}
 
-	return files, nil
+func (m *mixStats) merge(other *mixStats) {
+	if other == nil {
+		return
+	}
+	m.reads += other.reads
+	m.writes += other.writes
+	m.readBytes += other.readBytes
+	m.writeBytes += other.writeBytes
+	m.readTime += other.readTime
+	m.writeTime += other.writeTime
 }
 
-func runBenchmark(files []FileInfo, patternID int) (time.Duration, int64, error) {
-	accessOrder := createAccessPattern(files, patternID)
+// throughput returns the read and write MB/s over the time spent in each
+func (m *mixStats) throughput() (float64, float64) {
+	var readMBps, writeMBps float64
+	if m.readTime > 0 {
+		readMBps = float64(m.readBytes) / 1024 / 1024 / m.readTime.Seconds()
+	}
+	if m.writeTime > 0 {
+		writeMBps = float64(m.writeBytes) / 1024 / 1024 / m.writeTime.Seconds()
+	}
+	return readMBps, writeMBps
+}
 
-	startTime := time.Now()
-	totalBytes := int64(0)
+// phaseStats sums the time whole-file reads spent in each phase
+type phaseStats struct {
+	mu    sync.Mutex
+	files int64
+	open  time.Duration
+	stat  time.Duration
+	read  time.Duration
+	close time.Duration
+}
 
-	for _, idx := range accessOrder {
-		file := files[idx]
-		data, err := os.ReadFile(file.Path)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to read file %s: %w", file.Path, err)
-		}
-		totalBytes += int64(len(data))
+func (p *phaseStats) record(open, stat, read, close time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.files++
+	p.open += open
+	p.stat += stat
+	p.read += read
+	p.close += close
+}
+
+func (p *phaseStats) merge(other *phaseStats) {
+	if other == nil {
+		return
 	}
+	p.files += other.files
+	p.open += other.open
+	p.stat += other.stat
+	p.read += other.read
+	p.close += other.close
+}
 
-	duration := time.Since(startTime)
-	return duration, totalBytes, nil
+// means returns the mean open, stat, read and close time per file
+func (p *phaseStats) means() (time.Duration, time.Duration, time.Duration, time.Duration) {
+	if p.files == 0 {
+		return 0, 0, 0, 0
+	}
+	n := time.Duration(p.files)
+	return p.open / n, p.stat / n, p.read / n, p.close / n
 }
 
-func createAccessPattern(files []FileInfo, patternID int) []int {
-	n := len(files)
-	indices := make([]int, n)
+// newPhasedReadOp returns an op that reads whole files the way os.ReadFile
+// does, timing the open, stat, read and close calls of each separately
+func newPhasedReadOp(verify bool, calls *syscallCounter) (fileOp, *phaseStats) {
+	stats := &phaseStats{}
 
-	switch patternID {
-	case PatternSequential:
-		for i := 0; i < n; i++ {
-			indices[i] = i
+	op := func(file FileInfo) (int64, error) {
+		start := time.Now()
+		f, err := openCounted(file.Path, os.O_RDONLY, 0, calls)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open file %s: %w", file.Path, err)
 		}
-
-	case PatternReverseSeq:
-		for i := 0; i < n; i++ {
-			indices[i] = n - 1 - i
+		opened := time.Now()
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return 0, fmt.Errorf("failed to stat file %s: %w", file.Path, err)
 		}
+		statted := time.Now()
+		data := make([]byte, info.Size())
+		n, err := io.ReadFull(f, data)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			f.Close()
+			return int64(n), fmt.Errorf("failed to read file %s: %w", file.Path, err)
+		}
+		read := time.Now()
+		if err := f.Close(); err != nil {
+			return int64(n), fmt.Errorf("failed to close file %s: %w", file.Path, err)
+		}
+		closed := time.Now()
 
-	case PatternRandom:
-		for i := 0; i < n; i++ {
-			indices[i] = i
+		stats.record(opened.Sub(start), statted.Sub(opened), read.Sub(statted), closed.Sub(read))
+		if verify {
+			return int64(n), verifyChecksum(file, crc32.ChecksumIEEE(data[:n]))
 		}
-		rand.Shuffle(n, func(i, j int) {
-			indices[i], indices[j] = indices[j], indices[i]
-		})
+		return int64(n), nil
+	}
+	return op, stats
+}
+
+// newMixedOp returns an op that overwrites the file with probability
+// writeRatio and reads it otherwise, timing each kind separately
+func newMixedOp(read, write fileOp, rng *rand.Rand, writeRatio float64) (fileOp, *mixStats) {
+	var mu sync.Mutex
+	choiceRng := rand.New(rand.NewSource(rng.Int63()))
+	stats := &mixStats{}
 
-	case PatternZipfian:
-		// Zipfian distribution - some files accessed much more frequently
-		zipf := rand.NewZipf(rand.New(rand.NewSource(time.Now().UnixNano())), 1.1, 1.0, uint64(n-1))
-		for i := 0; i < n; i++ {
-			indices[i] = int(zipf.Uint64())
+	op := func(file FileInfo) (int64, error) {
+		mu.Lock()
+		isWrite := choiceRng.Float64() < writeRatio
+		mu.Unlock()
+
+		kind := read
+		if isWrite {
+			kind = write
+		}
+		start := time.Now()
+		n, err := kind(file)
+		if err != nil {
+			return n, err
 		}
+		stats.record(isWrite, n, time.Since(start))
+		return n, nil
+	}
+	return op, stats
+}
+
+// runConcurrent dispatches accessOrder across a pool of workers and times
+// from the first dispatch until the last worker finishes. Each operation
+// (holding one file open) takes one of slots while it runs.
+func runConcurrent(files []FileInfo, accessOrder accessSeq, streaming bool, workers int, slots chan struct{}, op fileOp, windows *windowRecorder, cache *lruSim, budget time.Duration, pace pacing) (IterationResult, error) {
+	jobs := make(chan int)
+	workerResults := make([]IterationResult, workers)
+	for w := range workerResults {
+		workerResults[w] = newIterationResult(accessSeq{}, streaming)
+	}
+	workerThought := make([]time.Duration, workers)
+	workerBytes := make([]int64, workers)
+	var totalBytes int64
+	var missing, timeouts int64
+	var firstErr error
+	var errOnce sync.Once
+	var wg sync.WaitGroup
+	var inflight sync.WaitGroup
 
-	case PatternLocalityBased:
-		// Access files in small groups, then jump
-		groupSize := 5
-		for i := 0; i < n; i++ {
-			group := (i / groupSize) * groupSize
-			offset := i % groupSize
-			indices[i] = group + offset
-			if indices[i] >= n {
-				indices[i] = n - 1
+	startTime := time.Now()
+	windows.begin(startTime)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for idx := range jobs {
+				if pace.think > 0 && workerResults[w].Ops > 0 {
+					workerThought[w] += pause(pace.think)
+				}
+				slots <- struct{}{}
+				opStart := time.Now()
+				n, err := op(files[idx])
+				releaseSlot(slots, err)
+				inflight.Done()
+				if errors.Is(err, fs.ErrNotExist) {
+					atomic.AddInt64(&missing, 1)
+					continue
+				}
+				if errors.Is(err, errReadTimeout) {
+					atomic.AddInt64(&timeouts, 1)
+					continue
+				}
+				if err != nil {
+					errOnce.Do(func() { firstErr = err })
+					continue
+				}
+				latency := time.Since(opStart)
+				workerResults[w].record(latency)
+				windows.add(n)
+				workerBytes[w] += n
+				atomic.AddInt64(&totalBytes, n)
+				verbosef("    worker %d %s: %d bytes in %s\n", w, files[idx].Path, n, latency)
 			}
+		}(w)
+	}
+
+	// The cache is simulated in issue order, which keeps it single-threaded.
+	// A burst ends once all of its operations have completed.
+	var idled time.Duration
+	for i := 0; keepIssuing(i, accessOrder.len(), startTime, budget); i++ {
+		if i > 0 && pace.burst > 0 && i%pace.burst == 0 {
+			inflight.Wait()
+			idled += pause(pace.idle)
 		}
+		idx := accessOrder.at(i % accessOrder.len())
+		cache.access(idx)
+		inflight.Add(1)
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+	wall := time.Since(startTime)
+
+	// Workers think in parallel, so the mean pause per worker comes off
+	var thought time.Duration
+	for _, t := range workerThought {
+		thought += t
+	}
+	duration := wall - idled - thought/time.Duration(workers)
+
+	if firstErr != nil {
+		return IterationResult{}, firstErr
+	}
 
-	case PatternRepeatedAccess:
-		// 80% of accesses to a hot set (top 10% of files)
-		hotSetSize := n / 10
-		if hotSetSize < 1 {
-			hotSetSize = 1
+	result := newIterationResult(accessOrder, streaming)
+	result.Workers = make([]WorkerLoad, workers)
+	for w, wr := range workerResults {
+		result.Ops += wr.Ops
+		result.Latencies = append(result.Latencies, wr.Latencies...)
+		if result.Histogram != nil {
+			result.Histogram.merge(wr.Histogram)
 		}
+		result.Workers[w] = WorkerLoad{Reads: wr.Ops, Bytes: workerBytes[w]}
+	}
+	result.Duration = duration
+	result.WallTime = wall
+	result.BytesRead = totalBytes
+	result.ErrorCount = missing
+	result.Timeouts = timeouts
+	result.Throughput = windows.series()
+	result.CacheHits = cache.hitCount()
+	return result, nil
+}
+
+// jainIndex returns Jain's fairness index of values, 1 when they're all
+// equal down to 1/n when one holds everything
+func jainIndex(values []int64) float64 {
+	var sum, sumSq float64
+	for _, v := range values {
+		sum += float64(v)
+		sumSq += float64(v) * float64(v)
+	}
+	if sumSq == 0 {
+		return 0
+	}
+	return sum * sum / (float64(len(values)) * sumSq)
+}
 
-		hotSet := make([]int, hotSetSize)
-		for i := 0; i < hotSetSize; i++ {
-			hotSet[i] = i
+// untouchedRanges lists the indices not set in touched as comma-separated
+// ranges, "" when every file was accessed
+func untouchedRanges(touched []bool) string {
+	var ranges []string
+	for i := 0; i < len(touched); i++ {
+		if touched[i] {
+			continue
+		}
+		start := i
+		for i+1 < len(touched) && !touched[i+1] {
+			i++
 		}
+		if start == i {
+			ranges = append(ranges, strconv.Itoa(i))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", start, i))
+		}
+	}
+	return strings.Join(ranges, ",")
+}
 
-		for i := 0; i < n; i++ {
-			if rand.Float32() < 0.8 {
-				indices[i] = hotSet[rand.Intn(hotSetSize)]
-			} else {
-				indices[i] = rand.Intn(n)
-			}
+// trimIterations returns the indices of durations left after dropping the
+// trim fraction of shortest and of longest ones, always keeping at least one
+func trimIterations(durations []time.Duration, trim float64) []int {
+	kept := make([]int, len(durations))
+	for i := range kept {
+		kept[i] = i
+	}
+	k := int(trim * float64(len(durations)))
+	if k == 0 || 2*k >= len(durations) {
+		return kept
+	}
+	sort.SliceStable(kept, func(a, b int) bool { return durations[kept[a]] < durations[kept[b]] })
+	return kept[k : len(kept)-k]
+}
+
+// durationStats returns the min, max and sample standard deviation (in
+// milliseconds) of per-iteration durations
+func durationStats(durations []time.Duration) (time.Duration, time.Duration, float64) {
+	if len(durations) == 0 {
+		return 0, 0, 0
+	}
+
+	minD, maxD := durations[0], durations[0]
+	var sum float64
+	for _, d := range durations {
+		if d < minD {
+			minD = d
 		}
+		if d > maxD {
+			maxD = d
+		}
+		sum += float64(d)
+	}
+	if len(durations) < 2 {
+		return minD, maxD, 0
+	}
 
-	default:
-		for i := 0; i < n; i++ {
-			indices[i] = i
+	mean := sum / float64(len(durations))
+	var sq float64
+	for _, d := range durations {
+		diff := float64(d) - mean
+		sq += diff * diff
+	}
+	stdDev := math.Sqrt(sq / float64(len(durations)-1))
+	return minD, maxD, stdDev / float64(time.Millisecond)
+}
+
+// coefficientOfVariation returns the sample standard deviation of durations
+// divided by their mean
+func coefficientOfVariation(durations []time.Duration) float64 {
+	if len(durations) < 2 {
+		return 0
+	}
+	var sum float64
+	for _, d := range durations {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(durations))
+	if mean == 0 {
+		return 0
+	}
+	_, _, stdDevMs := durationStats(durations)
+	return stdDevMs * float64(time.Millisecond) / mean
+}
+
+// percentile returns the nearest-rank p-th percentile of an ascending slice
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// createTargetDir creates dir and returns the topmost directory that did not
+// exist before, or "" if dir already existed
+func createTargetDir(dir string) (string, error) {
+	created := ""
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		created = d
+		if filepath.Dir(d) == d {
+			break
 		}
 	}
 
-	return indices
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return created, nil
+}
+
+// loadTrace reads a replay trace of file indices or generated file names,
+// one per line, checking each against config's file set. Blank lines and
+// lines starting with # are skipped.
+func loadTrace(path string, config BenchmarkConfig) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names := make(map[string]int, config.NumFiles)
+	for i := 0; i < config.NumFiles; i++ {
+		names[filepath.Base(testFilePath("", i, config))] = i
+	}
+
+	var order []int
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		idx, err := strconv.Atoi(entry)
+		if err != nil {
+			var ok bool
+			if idx, ok = names[filepath.Base(entry)]; !ok {
+				return nil, fmt.Errorf("%s:%d: %q is neither a file index nor a generated file name", path, line, entry)
+			}
+		}
+		if idx < 0 || idx >= config.NumFiles {
+			return nil, fmt.Errorf("%s:%d: file index %d out of range [0,%d)", path, line, idx, config.NumFiles)
+		}
+		order = append(order, idx)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("%s lists no file accesses", path)
+	}
+	return order, nil
 }
 
-func cleanupFiles(files []FileInfo) {
+// cleanupFiles removes the whole tree if the benchmark created it, otherwise
+// only the files it generated, so pre-existing data is never touched
+func cleanupFiles(files []FileInfo, dir, createdDir string) {
+	if createdDir != "" {
+		os.RemoveAll(createdDir)
+		return
+	}
+
+	root := filepath.Clean(dir)
 	for _, file := range files {
 		os.Remove(file.Path)
+		// Drop the tree directories as they empty out, Remove refuses
+		// anything that still has entries
+		for d := filepath.Dir(file.Path); d != root && len(d) > len(root); d = filepath.Dir(d) {
+			if os.Remove(d) != nil {
+				break
+			}
+		}
 	}
+}
 
-	if len(files) > 0 {
-		dir := filepath.Dir(files[0].Path)
-		os.Remove(dir)
-	}
-}
-
-func getPatternName(patternID int) string {
-	switch patternID {
-	case PatternSequential:
-		return "Sequential"
-	case PatternReverseSeq:
-		return "Reverse Sequential"
-	case PatternRandom:
-		return "Random"
-	case PatternZipfian:
-		return "Zipfian"
-	case PatternLocalityBased:
-		return "Locality-Based"
-	case PatternRepeatedAccess:
-		return "Repeated Access"
-	default:
-		return fmt.Sprintf("Unknown Pattern %d", patternID)
+// patternKey folds a pattern name for matching, so "Locality-Based",
+// "locality based" and "localitybased" are all the same pattern
+func patternKey(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name))
+}
+
+// parsePatternNames maps a comma-separated list of pattern names to IDs
+func parsePatternNames(list string) ([]int, error) {
+	byKey := make(map[string]int)
+	var valid []string
+	for id := PatternSequential; isKnownPattern(id); id++ {
+		byKey[patternKey(getPatternName(id))] = id
+		valid = append(valid, getPatternName(id))
+	}
+
+	var ids []int
+	for _, name := range strings.Split(list, ",") {
+		id, ok := byKey[patternKey(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown pattern %q, valid names are: %s", strings.TrimSpace(name), strings.Join(valid, ", "))
+		}
+		ids = append(ids, id)
 	}
+	return ids, nil
 }
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFiles(n int) []FileInfo {
+	files := make([]FileInfo, n)
+	for i := range files {
+		files[i] = FileInfo{Path: testFilePath("testdata", i, BenchmarkConfig{}), Size: 1024}
+	}
+	return files
+}
+
+func TestLocalityBasedClustersAndJumps(t *testing.T) {
+	files := testFiles(100)
+	rng := rand.New(rand.NewSource(1))
+	order := createAccessPattern(files, PatternLocalityBased, rng, BenchmarkConfig{})
+
+	if len(order) != len(files) {
+		t.Fatalf("got %d accesses, want %d", len(order), len(files))
+	}
+
+	sequential := true
+	for i, idx := range order {
+		if idx < 0 || idx >= len(files) {
+			t.Fatalf("access %d out of range: %d", i, idx)
+		}
+		if idx != i {
+			sequential = false
+		}
+	}
+	if sequential {
+		t.Fatal("order is strictly sequential, expected jumps between groups")
+	}
+
+	// Every group reads neighboring files one after another
+	for g := 0; g+localityGroupSize <= len(order); g += localityGroupSize {
+		for j := 1; j < localityGroupSize; j++ {
+			if order[g+j] != order[g+j-1]+1 {
+				t.Fatalf("group at %d is not contiguous: %v", g, order[g:g+localityGroupSize])
+			}
+		}
+	}
+}
+
+func testConfig() BenchmarkConfig {
+	config := newBenchmarkConfig()
+	config.setDefaults()
+	return config
+}
+
+func TestCreateAccessPatternInvariants(t *testing.T) {
+	tests := []struct {
+		pattern     int
+		exact       func(i, n int) int
+		permutation bool
+	}{
+		{pattern: PatternSequential, exact: func(i, n int) int { return i }},
+		{pattern: PatternReverseSeq, exact: func(i, n int) int { return n - 1 - i }},
+		{pattern: PatternWriteSequential, exact: func(i, n int) int { return i }},
+		{pattern: PatternRandom, permutation: true},
+		{pattern: PatternWriteRandom, permutation: true},
+		{pattern: PatternStrided, permutation: true},
+		{pattern: PatternMixed, permutation: true},
+		{pattern: PatternZipfian},
+		{pattern: PatternLocalityBased},
+		{pattern: PatternRepeatedAccess},
+		{pattern: PatternGaussian},
+		{pattern: PatternRecencyDecay},
+		{pattern: PatternTraceReplay},
+		{pattern: PatternBimodal},
+		{pattern: PatternSizeAscending, permutation: true},
+		{pattern: PatternSizeDescending, permutation: true},
+	}
+
+	config := testConfig()
+	for _, tt := range tests {
+		for _, n := range []int{1, 7, 100} {
+			t.Run(fmt.Sprintf("%s/%d", getPatternName(tt.pattern), n), func(t *testing.T) {
+				rng := rand.New(rand.NewSource(42))
+				order := createAccessPattern(testFiles(n), tt.pattern, rng, config)
+
+				if len(order) != n {
+					t.Fatalf("got %d accesses, want %d", len(order), n)
+				}
+				seen := make([]bool, n)
+				for i, idx := range order {
+					if idx < 0 || idx >= n {
+						t.Fatalf("access %d out of range [0,%d): %d", i, n, idx)
+					}
+					if tt.exact != nil && idx != tt.exact(i, n) {
+						t.Fatalf("access %d = %d, want %d", i, idx, tt.exact(i, n))
+					}
+					if (tt.exact != nil || tt.permutation) && seen[idx] {
+						t.Fatalf("file %d accessed twice in %v", idx, order)
+					}
+					seen[idx] = true
+				}
+			})
+		}
+	}
+}
+
+func TestCreateAccessPatternDeterministic(t *testing.T) {
+	config := testConfig()
+	files := testFiles(50)
+	for id := PatternSequential; isKnownPattern(id); id++ {
+		a := createAccessPattern(files, id, rand.New(rand.NewSource(7)), config)
+		b := createAccessPattern(files, id, rand.New(rand.NewSource(7)), config)
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("%s: orders differ at %d with the same seed", getPatternName(id), i)
+			}
+		}
+	}
+}
+
+func TestParsePatternNamesRoundTrip(t *testing.T) {
+	for id := PatternSequential; isKnownPattern(id); id++ {
+		name := getPatternName(id)
+		for _, spelled := range []string{name, strings.ToUpper(name), strings.ReplaceAll(name, " ", "-")} {
+			ids, err := parsePatternNames(spelled)
+			if err != nil || len(ids) != 1 || ids[0] != id {
+				t.Fatalf("parsePatternNames(%q) = %v, %v, want [%d]", spelled, ids, err, id)
+			}
+		}
+	}
+	if _, err := parsePatternNames("sequential,nope"); err == nil {
+		t.Fatal("unknown pattern name accepted")
+	}
+}
+
+func TestPatternSpecJSON(t *testing.T) {
+	var specs []PatternSpec
+	if err := json.Unmarshal([]byte(`[1, {"id": 4, "iterations": 50}]`), &specs); err != nil {
+		t.Fatal(err)
+	}
+	want := []PatternSpec{{ID: 1}, {ID: 4, Iterations: 50}}
+	if !slices.Equal(specs, want) {
+		t.Fatalf("got %v, want %v", specs, want)
+	}
+	data, err := json.Marshal(specs)
+	if err != nil || string(data) != `[1,{"id":4,"iterations":50}]` {
+		t.Fatalf("marshalled as %s, %v", data, err)
+	}
+}
+
+func TestRegisterPattern(t *testing.T) {
+	saved := patternRegistry
+	t.Cleanup(func() { patternRegistry = saved })
+	id := RegisterPattern("Test Every Other", "Every second file", func(files []FileInfo, rng *rand.Rand, cfg BenchmarkConfig) []int {
+		var order []int
+		for i := 0; i < len(files); i += 2 {
+			order = append(order, i)
+		}
+		return order
+	})
+	if !isKnownPattern(id) || getPatternName(id) != "Test Every Other" || getPatternDescription(id) != "Every second file" {
+		t.Fatalf("registered pattern %d not known as %q (%q)", id, getPatternName(id), getPatternDescription(id))
+	}
+	if ids, err := parsePatternNames("test-every-other"); err != nil || !slices.Equal(ids, []int{id}) {
+		t.Fatalf("parsePatternNames = %v, %v, want [%d]", ids, err, id)
+	}
+	order := createAccessPattern(testFiles(5), id, rand.New(rand.NewSource(1)), testConfig())
+	if !slices.Equal(order, []int{0, 2, 4}) {
+		t.Fatalf("got order %v", order)
+	}
+}
+
+func TestAccessSeqMatchesRepeat(t *testing.T) {
+	base := []int{3, 1, 2}
+	for _, interleaved := range []bool{false, true} {
+		seq := accessSeq{base: base, repeats: 3, interleaved: interleaved}
+		want := repeatAccessOrder(base, 3, interleaved)
+		if got := seq.expand(0); !slices.Equal(got, want) {
+			t.Fatalf("interleaved=%v: got %v, want %v", interleaved, got, want)
+		}
+	}
+}
+
+func TestLatencyHistogramPercentile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var h latencyHistogram
+	samples := make([]time.Duration, 100000)
+	for i := range samples {
+		samples[i] = time.Duration(rng.ExpFloat64() * float64(time.Millisecond))
+		h.record(samples[i])
+	}
+	slices.Sort(samples)
+	for _, p := range []float64{50, 95, 99, 99.9, 100} {
+		exact, got := percentile(samples, p), h.percentile(p)
+		if math.Abs(float64(got-exact)) > float64(exact)/100 {
+			t.Fatalf("p%v = %v, want %v within 1%%", p, got, exact)
+		}
+	}
+}
+
+func TestFormatInflux(t *testing.T) {
+	var results BenchmarkResults
+	results.System.Timestamp = "2024-01-02T03:04:05Z"
+	results.System.Hostname = "box"
+	results.Results = []BenchmarkResult{
+		{Pattern: "Repeated Access", Directory: "/mnt/a,b", Backend: "read", Concurrency: 1, MBytesPerSec: 1.5, P50: 2000},
+		{Pattern: "Random", Error: "failed"},
+	}
+	data, err := formatInflux(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `bench,pattern=Repeated\ Access,host=box,directory=/mnt/a\,b,backend=read,workers=1 mbytes_per_sec=1.5,`
+	if !strings.HasPrefix(string(data), want) || !strings.HasSuffix(string(data), " 1704164645000000000\n") || strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("got %q", data)
+	}
+}
+
+func TestUntouchedRanges(t *testing.T) {
+	touched := []bool{false, true, false, false, false, true, false}
+	if got := untouchedRanges(touched); got != "0,2-4,6" {
+		t.Fatalf("got %q", got)
+	}
+	if got := untouchedRanges([]bool{true, true}); got != "" {
+		t.Fatalf("got %q for full coverage", got)
+	}
+}
+
+func TestAppendHistoryKeep(t *testing.T) {
+	path := t.TempDir() + "/history.json"
+	for i := 1; i <= 4; i++ {
+		var results BenchmarkResults
+		results.System.Seed = int64(i)
+		history, err := appendHistory(path, results, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := min(i, 3); len(history) != want || history[len(history)-1].System.Seed != int64(i) {
+			t.Fatalf("after run %d: %d runs kept, want %d ending with run %d", i, len(history), want, i)
+		}
+	}
+}
+
+func TestWriteRatioZeroKept(t *testing.T) {
+	for data, want := range map[string]float64{`{"writeRatio": 0}`: 0, `{}`: 0.5} {
+		config := newBenchmarkConfig()
+		if err := json.Unmarshal([]byte(data), &config); err != nil {
+			t.Fatal(err)
+		}
+		config.setDefaults()
+		if config.WriteRatio != want {
+			t.Fatalf("%s: writeRatio = %g, want %g", data, config.WriteRatio, want)
+		}
+	}
+}
+
+func TestBimodalNoColdReads(t *testing.T) {
+	config := testConfig()
+	config.BimodalCold = 0
+	files := testFiles(1000)
+	for i, idx := range createAccessPattern(files, PatternBimodal, rand.New(rand.NewSource(3)), config) {
+		if !(idx < 100 || idx >= 500 && idx < 600) {
+			t.Fatalf("access %d went to cold file %d", i, idx)
+		}
+	}
+
+	config.BimodalSwitch = 0
+	for i, idx := range createAccessPattern(files, PatternBimodal, rand.New(rand.NewSource(3)), config) {
+		if idx >= 100 {
+			t.Fatalf("access %d left the first hot set for file %d", i, idx)
+		}
+	}
+}
+
+func TestCreateTestFilesSeeded(t *testing.T) {
+	config := testConfig()
+	sizes := []int64{1000, 5000, 70000}
+	var checksums [2][]uint32
+	for run := range checksums {
+		files, err := createTestFiles(t.TempDir(), sizes, config, rand.New(rand.NewSource(7)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			checksums[run] = append(checksums[run], file.Checksum)
+		}
+	}
+	if !slices.Equal(checksums[0], checksums[1]) {
+		t.Fatalf("same seed gave checksums %08x and %08x", checksums[0], checksums[1])
+	}
+}
+
+func TestSyscallCounterWholeRead(t *testing.T) {
+	files, err := createTestFiles(t.TempDir(), []int64{10000}, testConfig(), rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := &syscallCounter{}
+	if _, err := newWholeReadOp(true, calls)(files[0]); err != nil {
+		t.Fatal(err)
+	}
+	// An fstat sizes the buffer, one read returns the data, the next EOF
+	if want := (SyscallCounts{Open: 1, Read: 2, Close: 1, Stat: 1}); *calls.totals() != want {
+		t.Fatalf("counted %+v, want %+v", *calls.totals(), want)
+	}
+}
+
+func TestTimedOutOpKeepsSlot(t *testing.T) {
+	slots := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	returned := make(chan struct{})
+	op := withTimeout(func(file FileInfo) (int64, error) {
+		defer close(returned)
+		<-unblock
+		return 0, nil
+	}, time.Millisecond, slots)
+
+	slots <- struct{}{}
+	_, err := op(FileInfo{Path: "hung"})
+	releaseSlot(slots, err)
+	if !errors.Is(err, errReadTimeout) || len(slots) != 1 {
+		t.Fatalf("err %v, %d slots held, want a timeout still holding its slot", err, len(slots))
+	}
+	close(unblock)
+	<-returned
+	for deadline := time.Now().Add(time.Second); len(slots) != 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("slot not released once the abandoned op returned")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScalarBaselineReadsSameRanges(t *testing.T) {
+	config := testConfig()
+	config.Backend = "preadv"
+	config.IovecKB = 4
+	config.BlockOffsets = "random"
+	config.BlockAlign = 512
+	config.BlockMisalign = true
+	config.Verify = true
+	config.setDefaults()
+	files, err := createTestFiles(t.TempDir(), []int64{1000, 10000, 70001}, config, rand.New(rand.NewSource(5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scalar := config.scalarBaseline()
+	// Verify checks each file's CRC32 over the bytes in read order, so it
+	// only passes if both read every byte front to back
+	for _, c := range []BenchmarkConfig{config, scalar} {
+		iter, err := runBenchmark(files, PatternSequential, rand.New(rand.NewSource(1)), c, newRunResources(files, c))
+		if err != nil {
+			t.Fatalf("%s backend: %v", c.Backend, err)
+		}
+		if iter.BytesRead != 81001 {
+			t.Fatalf("%s backend read %d bytes, want 81001", c.Backend, iter.BytesRead)
+		}
+	}
+}
+
+func TestGaussianCenterDrifts(t *testing.T) {
+	config := testConfig()
+	config.GaussianStdDev = 1e-9
+	config.GaussianDrift = 3
+	files := testFiles(10)
+	for i, idx := range createAccessPattern(files, PatternGaussian, rand.New(rand.NewSource(1)), config) {
+		// A pick just below the center floors to the file before it
+		if want := (5 + 3*i) % 10; idx != want && idx != (want+9)%10 {
+			t.Fatalf("access %d went to file %d, want %d or the one before around the moving center", i, idx, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build linux && (amd64 || arm64)
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+const coldCacheSupported = true
+
+const fadviseSupported = true
+
+const (
+	fadvSequential = 2
+	fadvDontNeed   = 4
+)
+
+// dropPageCache evicts the whole page cache when running as root, otherwise
+// it falls back to advising the kernel to drop just the benchmark files
+func dropPageCache(files []FileInfo) error {
+	syscall.Sync()
+	if err := os.WriteFile("/proc/sys/vm/drop_caches", []byte("3"), 0644); err == nil {
+		return nil
+	}
+
+	for _, file := range files {
+		f, err := os.Open(file.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", file.Path, err)
+		}
+		err = fadvise(f, fadvDontNeed)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("fadvise DONTNEED on %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// fadvise applies advice to the whole of f
+func fadvise(f *os.File, advice int) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), 0, 0, uintptr(advice), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
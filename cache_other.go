@@ -0,0 +1,23 @@
+//go:build !linux || !(amd64 || arm64)
+
+package main
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+const coldCacheSupported = false
+
+const fadviseSupported = false
+
+const fadvSequential = 2
+
+func dropPageCache(files []FileInfo) error {
+	return errors.New("dropping the page cache is unsupported on " + runtime.GOOS + "/" + runtime.GOARCH)
+}
+
+func fadvise(f *os.File, advice int) error {
+	return errors.New("fadvise is unsupported on " + runtime.GOOS + "/" + runtime.GOARCH)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// exitRegression is the exit status when a comparison finds a regression,
+// other failures exit with 1
+const exitRegression = 2
+
+func loadResults(path string) (BenchmarkResults, error) {
+	var results BenchmarkResults
+	data, err := readFileGz(path)
+	if err != nil {
+		return results, err
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return results, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return results, nil
+}
+
+// runCompareCommand implements "bench compare baseline.json current.json"
+func runCompareCommand(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	threshold := fs.Float64("fail-threshold", 5, "Flag MB/s drops larger than this percentage as regressions and exit with status 2")
+	fs.Float64Var(threshold, "threshold", 5, "Alias of -fail-threshold")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s compare [-fail-threshold pct] baseline.json current.json\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	// Parse stops at the first path, flags after the paths are parsed too
+	var paths []string
+	for fs.Parse(args); fs.NArg() > 0; fs.Parse(args) {
+		paths = append(paths, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(paths) != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	baseline, err := loadResults(paths[0])
+	if err != nil {
+		errorf("Error loading baseline: %v\n", err)
+		os.Exit(1)
+	}
+	current, err := loadResults(paths[1])
+	if err != nil {
+		errorf("Error loading results: %v\n", err)
+		os.Exit(1)
+	}
+
+	if regressions := compareResults(baseline, current, *threshold); len(regressions) > 0 {
+		os.Exit(exitRegression)
+	}
+}
+
+// compareResults prints the per-pattern change from baseline to current and
+// returns the patterns whose MB/s dropped by more than threshold percent.
+// Results are matched by pattern name, repeated names (several directories)
+// pair up in order of appearance.
+func compareResults(baseline, current BenchmarkResults, threshold float64) []string {
+	matchKeys := func(results []BenchmarkResult) []string {
+		seen := make(map[string]int)
+		keys := make([]string, len(results))
+		for i, result := range results {
+			keys[i] = fmt.Sprintf("%s#%d", result.Pattern, seen[result.Pattern])
+			seen[result.Pattern]++
+		}
+		return keys
+	}
+
+	baseByKey := make(map[string]BenchmarkResult)
+	for i, key := range matchKeys(baseline.Results) {
+		baseByKey[key] = baseline.Results[i]
+	}
+
+	fmt.Println("\nComparison against baseline:")
+	fmt.Println("Pattern               | Base MB/s | New MB/s  | Δ MB/s   | Δ%      | Base r/s  | New r/s   | Δ%")
+	fmt.Println("----------------------|-----------|-----------|----------|---------|-----------|-----------|---------")
+
+	var regressions []string
+	for i, key := range matchKeys(current.Results) {
+		cur := current.Results[i]
+		base, ok := baseByKey[key]
+		if !ok {
+			fmt.Printf("%-20s | %9s | %9.2f | %8s | %7s | %9s | %9.2f | %7s\n",
+				cur.Pattern, "-", cur.MBytesPerSec, "-", "-", "-", cur.ReadPerSec, "-")
+			continue
+		}
+
+		mbDelta := percentChange(base.MBytesPerSec, cur.MBytesPerSec)
+		readDelta := percentChange(base.ReadPerSec, cur.ReadPerSec)
+		marker := ""
+		if mbDelta < -threshold {
+			marker = "  REGRESSION"
+			regressions = append(regressions, cur.Pattern)
+		}
+		fmt.Printf("%-20s | %9.2f | %9.2f | %+8.2f | %+6.1f%% | %9.2f | %9.2f | %+6.1f%%%s\n",
+			cur.Pattern, base.MBytesPerSec, cur.MBytesPerSec, cur.MBytesPerSec-base.MBytesPerSec,
+			mbDelta, base.ReadPerSec, cur.ReadPerSec, readDelta, marker)
+	}
+
+	if len(regressions) > 0 {
+		fmt.Printf("\n%d pattern(s) regressed by more than %.1f%% MB/s: %s\n", len(regressions), threshold, strings.Join(regressions, ", "))
+	}
+	return regressions
+}
+
+func percentChange(base, current float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return (current - base) / base * 100
+}
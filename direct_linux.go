@@ -0,0 +1,13 @@
+//go:build linux
+
+package main
+
+import "syscall"
+
+const directIOSupported = true
+
+// directIOFlag bypasses the page cache. Buffers, lengths and offsets must be
+// multiples of directIOAlign.
+const directIOFlag = syscall.O_DIRECT
+
+const directIOAlign = 4096
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+const directIOSupported = false
+
+const directIOFlag = 0
+
+const directIOAlign = 4096
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+func freeDiskSpace(path string) (uint64, bool) {
+	return 0, false
+}
+
+func filesystemID(path string) (uint64, bool) {
+	return 0, false
+}
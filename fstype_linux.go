@@ -0,0 +1,51 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// filesystemMagics maps statfs f_type values to filesystem names, see
+// include/uapi/linux/magic.h
+var filesystemMagics = map[uint32]string{
+	0xEF53:     "ext2/3/4",
+	0x58465342: "xfs",
+	0x9123683E: "btrfs",
+	0x2FC12FC1: "zfs",
+	0xF2F52010: "f2fs",
+	0xCA451A4E: "bcachefs",
+	0x01021994: "tmpfs",
+	0x858458F6: "ramfs",
+	0x794C7630: "overlayfs",
+	0x6969:     "nfs",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x00C36400: "ceph",
+	0x01021997: "9p",
+	0x65735546: "fuse",
+	0x4D44:     "vfat",
+	0x2011BAB0: "exfat",
+	0x5346544E: "ntfs",
+	0x73717368: "squashfs",
+	0xE0F5E1E2: "erofs",
+	0x9660:     "iso9660",
+	0x52654973: "reiserfs",
+	0x3153464A: "jfs",
+	0x482B:     "hfsplus",
+}
+
+// filesystemType names the filesystem holding dir, or gives its raw magic
+// number when it isn't a known one
+func filesystemType(dir string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return "", false
+	}
+	magic := uint32(st.Type)
+	if name, ok := filesystemMagics[magic]; ok {
+		return name, true
+	}
+	return fmt.Sprintf("0x%X", magic), true
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+func filesystemType(dir string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"strings"
+)
+
+// appendHistory adds results to the JSON array of runs at path, creating it
+// if needed, and drops the oldest runs beyond keep (0 keeps them all). The
+// file is replaced through a rename so an interrupted write can't truncate
+// the history.
+func appendHistory(path string, results BenchmarkResults, keep int) ([]BenchmarkResults, error) {
+	var history []BenchmarkResults
+	data, err := readFileGz(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &history); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+
+	history = append(history, results)
+	if keep > 0 && len(history) > keep {
+		history = history[len(history)-keep:]
+	}
+
+	data, err = json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	// The temporary name keeps the extension so it is compressed the same
+	tmp := strings.TrimSuffix(path, ".gz") + ".tmp"
+	if strings.HasSuffix(path, ".gz") {
+		tmp += ".gz"
+	}
+	if err := writeFileGz(tmp, data); err != nil {
+		return nil, err
+	}
+	return history, os.Rename(tmp, path)
+}
+
+// printTrend prints each pattern's MB/s over the runs in history, oldest
+// first, as a sparkline with the first, latest and best values. A pattern
+// measured several times in one run counts with its mean.
+func printTrend(history []BenchmarkResults) {
+	levels := []rune("▁▂▃▄▅▆▇█")
+	type trendKey struct{ dir, pattern string }
+	var order []trendKey
+	series := make(map[trendKey][]float64)
+	for run, results := range history {
+		sums := make(map[trendKey]float64)
+		counts := make(map[trendKey]int)
+		for _, result := range results.Results {
+			if result.Error != "" {
+				continue
+			}
+			k := trendKey{result.Directory, result.Pattern}
+			if _, ok := series[k]; !ok {
+				order = append(order, k)
+				series[k] = nil
+			}
+			sums[k] += result.MBytesPerSec
+			counts[k]++
+		}
+		for k := range series {
+			// Runs that didn't measure a pattern leave a gap
+			for len(series[k]) < run {
+				series[k] = append(series[k], math.NaN())
+			}
+			if counts[k] > 0 {
+				series[k] = append(series[k], sums[k]/float64(counts[k]))
+			}
+		}
+	}
+
+	dirs := make(map[string]bool)
+	for _, k := range order {
+		dirs[k.dir] = true
+	}
+	fmt.Printf("\nTrend over %d runs (MB/s, oldest first):\n", len(history))
+	fmt.Printf("%-22s %-*s %9s %9s %9s %8s\n", "Pattern", len(history), "", "First", "Latest", "Best", "Δ%")
+	for _, k := range order {
+		values := series[k]
+		for len(values) < len(history) {
+			values = append(values, math.NaN())
+		}
+		lo, hi := math.Inf(1), math.Inf(-1)
+		first, latest := math.NaN(), math.NaN()
+		for _, v := range values {
+			if math.IsNaN(v) {
+				continue
+			}
+			if math.IsNaN(first) {
+				first = v
+			}
+			latest = v
+			lo, hi = min(lo, v), max(hi, v)
+		}
+
+		var spark strings.Builder
+		for _, v := range values {
+			switch {
+			case math.IsNaN(v):
+				spark.WriteRune(' ')
+			case hi == lo:
+				spark.WriteRune(levels[len(levels)/2])
+			default:
+				spark.WriteRune(levels[int((v-lo)/(hi-lo)*float64(len(levels)-1)+0.5)])
+			}
+		}
+
+		label := k.pattern
+		if len(dirs) > 1 {
+			label += " (" + k.dir + ")"
+		}
+		fmt.Printf("%-22s %s %9.2f %9.2f %9.2f %+7.1f%%\n", label, spark.String(), first, latest, hi, percentChange(first, latest))
+	}
+}
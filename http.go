@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// startHTTPServer serves the runner's live results on /results and a
+// liveness check on /healthz
+func startHTTPServer(addr string, runner *benchRunner) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/results", func(w http.ResponseWriter, req *http.Request) {
+		data, err := runner.snapshot()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errorf("Error serving HTTP on %s: %v\n", addr, err)
+		}
+	}()
+	logf("Serving live results on http://%s/results\n", addr)
+	return server
+}
+
+func stopHTTPServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		errorf("Error shutting down HTTP server: %v\n", err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// influxEscaper escapes tag keys and values for InfluxDB line protocol
+var influxEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// formatInflux renders each successful result as one line protocol point,
+// all stamped with the run's System timestamp. Directory, backend, workers
+// and run are tags too so points of the same pattern don't overwrite each
+// other.
+func formatInflux(results BenchmarkResults) ([]byte, error) {
+	ts, err := time.Parse(time.RFC3339, results.System.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("bad timestamp %q: %w", results.System.Timestamp, err)
+	}
+
+	var buf bytes.Buffer
+	for _, result := range results.Results {
+		if result.Error != "" {
+			continue
+		}
+		buf.WriteString("bench")
+		tag := func(key, value string) {
+			if value != "" {
+				fmt.Fprintf(&buf, ",%s=%s", key, influxEscaper.Replace(value))
+			}
+		}
+		tag("pattern", result.Pattern)
+		tag("host", results.System.Hostname)
+		tag("directory", result.Directory)
+		tag("backend", result.Backend)
+		tag("workers", strconv.Itoa(result.Concurrency))
+		if result.Run > 0 {
+			tag("run", strconv.Itoa(result.Run))
+		}
+		fmt.Fprintf(&buf, " mbytes_per_sec=%g,reads_per_sec=%g,iops=%g,bytes_read=%di,duration_sec=%g,p50_ns=%di,p95_ns=%di,p99_ns=%di,max_latency_ns=%di,iterations=%di %d\n",
+			result.MBytesPerSec, result.ReadPerSec, result.IOPS, result.BytesRead, result.Duration.Seconds(),
+			result.P50.Nanoseconds(), result.P95.Nanoseconds(), result.P99.Nanoseconds(), result.MaxLatency.Nanoseconds(),
+			result.Iterations, ts.UnixNano())
+	}
+	return buf.Bytes(), nil
+}
+
+func writeInflux(path string, results BenchmarkResults) error {
+	data, err := formatInflux(results)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// postInflux sends the results to an InfluxDB write endpoint, e.g.
+// http://localhost:8086/api/v2/write?org=o&bucket=b&precision=ns or
+// http://localhost:8086/write?db=bench
+func postInflux(url string, results BenchmarkResults) error {
+	data, err := formatInflux(results)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token := os.Getenv("INFLUX_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("InfluxDB returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const ioUringSupported = true
+
+// io_uring is driven through the raw syscalls and the rings the kernel
+// shares over mmap, see include/uapi/linux/io_uring.h
+const (
+	sysIOURingSetup = 425
+	sysIOURingEnter = 426
+
+	ioringOffSQRing = 0
+	ioringOffCQRing = 0x8000000
+	ioringOffSQEs   = 0x10000000
+
+	ioringOpRead         = 22
+	ioringEnterGetEvents = 1
+
+	sqeSize = 64
+	cqeSize = 16
+)
+
+type ioUringParams struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFD         uint32
+	resv         [3]uint32
+	sqOff        struct {
+		head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+		userAddr                                                        uint64
+	}
+	cqOff struct {
+		head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+		userAddr                                                        uint64
+	}
+}
+
+type ioUring struct {
+	fd     int
+	sqRing []byte
+	cqRing []byte
+	sqes   []byte
+	params ioUringParams
+}
+
+func newIOURing(entries int) (*ioUring, error) {
+	r := &ioUring{}
+	fd, _, errno := syscall.Syscall(sysIOURingSetup, uintptr(entries), uintptr(unsafe.Pointer(&r.params)), 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("io_uring_setup: %w", errno)
+	}
+	r.fd = int(fd)
+
+	p := &r.params
+	var err error
+	r.sqRing, err = syscall.Mmap(r.fd, ioringOffSQRing, int(p.sqOff.array+p.sqEntries*4), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	if err == nil {
+		r.cqRing, err = syscall.Mmap(r.fd, ioringOffCQRing, int(p.cqOff.cqes+p.cqEntries*cqeSize), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	}
+	if err == nil {
+		r.sqes, err = syscall.Mmap(r.fd, ioringOffSQEs, int(p.sqEntries*sqeSize), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	}
+	if err != nil {
+		r.close()
+		return nil, fmt.Errorf("mapping io_uring: %w", err)
+	}
+	return r, nil
+}
+
+func (r *ioUring) close() {
+	for _, m := range [][]byte{r.sqes, r.cqRing, r.sqRing} {
+		if m != nil {
+			syscall.Munmap(m)
+		}
+	}
+	syscall.Close(r.fd)
+}
+
+func ringWord(ring []byte, off uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&ring[off]))
+}
+
+// queueRead adds a read of buf from fd at off to the submission ring. The
+// caller keeps buf alive until its completion is reaped.
+func (r *ioUring) queueRead(fd int, buf []byte, off int64, tag uint64) {
+	p := &r.params
+	tail := atomic.LoadUint32(ringWord(r.sqRing, p.sqOff.tail))
+	slot := tail & *ringWord(r.sqRing, p.sqOff.ringMask)
+
+	sqe := r.sqes[slot*sqeSize : (slot+1)*sqeSize]
+	clear(sqe)
+	sqe[0] = ioringOpRead
+	*(*int32)(unsafe.Pointer(&sqe[4])) = int32(fd)
+	*(*uint64)(unsafe.Pointer(&sqe[8])) = uint64(off)
+	*(*uint64)(unsafe.Pointer(&sqe[16])) = uint64(uintptr(unsafe.Pointer(&buf[0])))
+	*(*uint32)(unsafe.Pointer(&sqe[24])) = uint32(len(buf))
+	*(*uint64)(unsafe.Pointer(&sqe[32])) = tag
+
+	*ringWord(r.sqRing, p.sqOff.array+slot*4) = slot
+	atomic.StoreUint32(ringWord(r.sqRing, p.sqOff.tail), tail+1)
+}
+
+// submit hands the kernel the queued reads and waits for one to complete
+func (r *ioUring) submit(queued int, calls *syscallCounter) error {
+	for {
+		calls.add(sysURingEnter, 1)
+		_, _, errno := syscall.Syscall6(sysIOURingEnter, uintptr(r.fd), uintptr(queued), 1, ioringEnterGetEvents, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return fmt.Errorf("io_uring_enter: %w", errno)
+		}
+		return nil
+	}
+}
+
+// reap calls fn for every completion waiting in the ring
+func (r *ioUring) reap(fn func(tag uint64, res int32) error) error {
+	p := &r.params
+	headPtr := ringWord(r.cqRing, p.cqOff.head)
+	head := atomic.LoadUint32(headPtr)
+	tail := atomic.LoadUint32(ringWord(r.cqRing, p.cqOff.tail))
+	mask := *ringWord(r.cqRing, p.cqOff.ringMask)
+	for ; head != tail; head++ {
+		cqe := r.cqRing[p.cqOff.cqes+(head&mask)*cqeSize:]
+		tag := *(*uint64)(unsafe.Pointer(&cqe[0]))
+		res := *(*int32)(unsafe.Pointer(&cqe[8]))
+		if err := fn(tag, res); err != nil {
+			atomic.StoreUint32(headPtr, head+1)
+			return err
+		}
+	}
+	atomic.StoreUint32(headPtr, head)
+	return nil
+}
+
+// uringRead is one whole-file read in flight
+type uringRead struct {
+	idx   int
+	f     *countedFile
+	buf   []byte
+	done  int
+	start time.Time
+}
+
+// runIOURing reads whole files in accessOrder, keeping up to depth reads
+// queued in one io_uring. Opening each file stays a synchronous call.
+func runIOURing(files []FileInfo, accessOrder accessSeq, streaming bool, depth int, verify bool, windows *windowRecorder, cache *lruSim, budget time.Duration, calls *syscallCounter) (IterationResult, error) {
+	ring, err := newIOURing(depth)
+	if err != nil {
+		return IterationResult{}, err
+	}
+	defer ring.close()
+
+	slots := make([]uringRead, depth)
+	free := make([]int, depth)
+	for i := range free {
+		free[i] = depth - 1 - i
+	}
+	defer func() {
+		for i := range slots {
+			if slots[i].f != nil {
+				slots[i].f.Close()
+			}
+		}
+	}()
+
+	result := newIterationResult(accessOrder, streaming)
+	finish := func(s *uringRead) error {
+		latency := time.Since(s.start)
+		s.f.Close()
+		s.f = nil
+		file := files[s.idx]
+		if verify {
+			if err := verifyChecksum(file, crc32.ChecksumIEEE(s.buf)); err != nil {
+				return err
+			}
+		}
+		result.record(latency)
+		windows.add(int64(len(s.buf)))
+		result.BytesRead += int64(len(s.buf))
+		verbosef("    %s: %d bytes in %s\n", file.Path, len(s.buf), latency)
+		return nil
+	}
+
+	startTime := time.Now()
+	windows.begin(startTime)
+	queued, inflight := 0, 0
+	for i := 0; ; {
+		for len(free) > 0 && keepIssuing(i, accessOrder.len(), startTime, budget) {
+			idx := accessOrder.at(i % accessOrder.len())
+			i++
+			cache.access(idx)
+
+			slot := free[len(free)-1]
+			s := &slots[slot]
+			s.start = time.Now()
+			f, err := openCounted(files[idx].Path, os.O_RDONLY, 0, calls)
+			if errors.Is(err, fs.ErrNotExist) {
+				result.ErrorCount++
+				continue
+			}
+			if err != nil {
+				return IterationResult{}, fmt.Errorf("failed to open file %s: %w", files[idx].Path, err)
+			}
+			free = free[:len(free)-1]
+			s.idx, s.f, s.done = idx, f, 0
+			if int64(cap(s.buf)) < files[idx].Size {
+				s.buf = make([]byte, files[idx].Size)
+			}
+			s.buf = s.buf[:files[idx].Size]
+			if len(s.buf) == 0 {
+				if err := finish(s); err != nil {
+					return IterationResult{}, err
+				}
+				free = append(free, slot)
+				continue
+			}
+			ring.queueRead(int(f.Fd()), s.buf, 0, uint64(slot))
+			queued++
+			inflight++
+		}
+		if inflight == 0 {
+			break
+		}
+
+		if err := ring.submit(queued, calls); err != nil {
+			return IterationResult{}, err
+		}
+		queued = 0
+		err := ring.reap(func(tag uint64, res int32) error {
+			s := &slots[tag]
+			result.IOs++
+			if res < 0 {
+				return fmt.Errorf("failed to read file %s: %w", files[s.idx].Path, syscall.Errno(-res))
+			}
+			if res == 0 {
+				return fmt.Errorf("failed to read file %s: file is shorter than %d bytes", files[s.idx].Path, len(s.buf))
+			}
+			s.done += int(res)
+			if s.done < len(s.buf) {
+				ring.queueRead(int(s.f.Fd()), s.buf[s.done:], int64(s.done), tag)
+				queued++
+				return nil
+			}
+			inflight--
+			free = append(free, int(tag))
+			return finish(s)
+		})
+		if err != nil {
+			return IterationResult{}, err
+		}
+	}
+
+	result.WallTime = time.Since(startTime)
+	result.Duration = result.WallTime
+	result.Throughput = windows.series()
+	result.CacheHits = cache.hitCount()
+	return result, nil
+}
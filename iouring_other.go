@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"runtime"
+	"time"
+)
+
+const ioUringSupported = false
+
+func runIOURing(files []FileInfo, accessOrder accessSeq, streaming bool, depth int, verify bool, windows *windowRecorder, cache *lruSim, budget time.Duration, calls *syscallCounter) (IterationResult, error) {
+	return IterationResult{}, errors.New("io_uring backend is unsupported on " + runtime.GOOS)
+}
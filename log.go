@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// logLevel controls how much progress output is printed. Results written
+// to files and the final summary are not affected.
+type logLevel int
+
+const (
+	levelQuiet   logLevel = iota // errors and warnings only
+	levelNormal                  // progress messages
+	levelVerbose                 // progress plus per-read timing
+)
+
+var currentLevel = levelNormal
+
+// logf prints progress output shown at the default level
+func logf(format string, args ...any) {
+	if currentLevel >= levelNormal {
+		fmt.Printf(format, args...)
+	}
+}
+
+// verbosef prints detail only shown with -v
+func verbosef(format string, args ...any) {
+	if currentLevel >= levelVerbose {
+		fmt.Printf(format, args...)
+	}
+}
+
+// errorf prints errors and warnings to stderr, they are shown at every level
+func errorf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
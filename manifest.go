@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Manifest records a generated dataset. The seed reproduces the file names,
+// sizes and content, each file's CRC32 is kept to check it later.
+type Manifest struct {
+	Seed  int64           `json:"seed"`
+	Files []ManifestEntry `json:"files"`
+
+	// loaded is set when the manifest was read from disk rather than
+	// built by this run
+	loaded bool
+}
+
+type ManifestEntry struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	CRC32 string `json:"crc32"`
+}
+
+// loadManifest reads the manifest at path, returning an empty one for seed
+// if it doesn't exist yet
+func loadManifest(path string, seed int64) (*Manifest, error) {
+	data, err := readFileGz(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Manifest{Seed: seed}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	m.loaded = true
+	return &m, nil
+}
+
+// add records files, replacing any earlier entries for the same paths
+func (m *Manifest) add(files []FileInfo) {
+	index := make(map[string]int, len(m.Files))
+	for i, entry := range m.Files {
+		index[entry.Path] = i
+	}
+	for _, file := range files {
+		entry := ManifestEntry{Path: file.Path, Size: file.Size, CRC32: fmt.Sprintf("%08x", file.Checksum)}
+		if i, ok := index[file.Path]; ok {
+			m.Files[i] = entry
+			continue
+		}
+		m.Files = append(m.Files, entry)
+	}
+}
+
+// apply fills in the checksums of files from the manifest and fails if any
+// file is missing from it or has a different size
+func (m *Manifest) apply(files []FileInfo) error {
+	entries := make(map[string]ManifestEntry, len(m.Files))
+	for _, entry := range m.Files {
+		entries[entry.Path] = entry
+	}
+	for i, file := range files {
+		entry, ok := entries[file.Path]
+		if !ok {
+			return fmt.Errorf("%s is not in the manifest", file.Path)
+		}
+		if entry.Size != file.Size {
+			return fmt.Errorf("%s is %d bytes, the manifest says %d", file.Path, file.Size, entry.Size)
+		}
+		var checksum uint32
+		if _, err := fmt.Sscanf(entry.CRC32, "%08x", &checksum); err != nil {
+			return fmt.Errorf("bad checksum %q for %s in the manifest", entry.CRC32, file.Path)
+		}
+		files[i].Checksum = checksum
+	}
+	return nil
+}
+
+func (m *Manifest) write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileGz(path, data)
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"runtime"
+)
+
+const mmapSupported = false
+
+func newMmapReadOp(verify bool, calls *syscallCounter) fileOp {
+	return func(file FileInfo) (int64, error) {
+		return 0, errors.New("mmap backend is unsupported on " + runtime.GOOS)
+	}
+}
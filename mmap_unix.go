@@ -0,0 +1,60 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+const mmapSupported = true
+
+// newMmapReadOp returns an op that maps each file read-only and touches one
+// byte per page so every page is faulted in, or with verify hashes all of
+// it, which faults them in too
+func newMmapReadOp(verify bool, calls *syscallCounter) fileOp {
+	return func(file FileInfo) (int64, error) {
+		return mmapFile(file, verify, calls)
+	}
+}
+
+func mmapFile(file FileInfo, verify bool, calls *syscallCounter) (int64, error) {
+	if file.Size == 0 {
+		if verify {
+			return 0, verifyChecksum(file, 0)
+		}
+		return 0, nil
+	}
+
+	f, err := openCounted(file.Path, os.O_RDONLY, 0, calls)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file %s: %w", file.Path, err)
+	}
+	defer f.Close()
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(file.Size), syscall.PROT_READ, syscall.MAP_SHARED)
+	calls.add(sysMmap, 1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mmap file %s: %w", file.Path, err)
+	}
+	defer func() {
+		syscall.Munmap(data)
+		calls.add(sysMmap, 1)
+	}()
+
+	if verify {
+		return int64(len(data)), verifyChecksum(file, crc32.ChecksumIEEE(data))
+	}
+
+	pageSize := os.Getpagesize()
+	var sum byte
+	for i := 0; i < len(data); i += pageSize {
+		sum += data[i]
+	}
+	runtime.KeepAlive(sum)
+
+	return int64(len(data)), nil
+}
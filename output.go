@@ -0,0 +1,229 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// writeCSV writes one row per result, preceded by the config and system
+// metadata as '#'-prefixed comment lines
+func writeCSV(path string, results BenchmarkResults) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	configData, err := json.Marshal(results.Config)
+	if err != nil {
+		return err
+	}
+	systemData, err := json.Marshal(results.System)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(f, "# config: %s\n", configData)
+	fmt.Fprintf(f, "# system: %s\n", systemData)
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"pattern", "directory", "duration_sec", "file_count", "bytes_read", "reads_per_sec", "mbytes_per_sec"})
+	for _, result := range results.Results {
+		w.Write([]string{
+			result.Pattern,
+			result.Directory,
+			strconv.FormatFloat(result.Duration.Seconds(), 'f', 6, 64),
+			strconv.Itoa(result.FileCount),
+			strconv.FormatInt(result.BytesRead, 10),
+			strconv.FormatFloat(result.ReadPerSec, 'f', 2, 64),
+			strconv.FormatFloat(result.MBytesPerSec, 'f', 2, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeMarkdown writes a GitHub-flavored Markdown report with the system
+// info, the config, and a table of the per-pattern results
+func writeMarkdown(path string, results BenchmarkResults) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	configData, err := json.MarshalIndent(results.Config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	system := results.System
+	fmt.Fprintf(f, "## Benchmark results\n\n")
+	fmt.Fprintf(f, "- Timestamp: %s\n", system.Timestamp)
+	fmt.Fprintf(f, "- Host: %s (%s/%s, %d CPUs)\n", system.Hostname, system.OS, system.Arch, system.NumCPU)
+	if system.CPUModel != "" {
+		fmt.Fprintf(f, "- CPU: %s\n", system.CPUModel)
+	}
+	if system.TotalRAM > 0 {
+		fmt.Fprintf(f, "- RAM: %.1f GiB\n", float64(system.TotalRAM)/(1<<30))
+	}
+	fmt.Fprintf(f, "- Go: %s\n", system.GoVersion)
+	for _, dir := range results.Config.TargetDirectory {
+		if fsType, ok := system.Filesystems[dir]; ok {
+			fmt.Fprintf(f, "- Filesystem of %s: %s\n", dir, fsType)
+		}
+	}
+	fmt.Fprintf(f, "- Seed: %d\n", system.Seed)
+	if results.Partial {
+		fmt.Fprintf(f, "- **Partial run**, interrupted before all patterns finished\n")
+	}
+
+	fmt.Fprintf(f, "\n<details><summary>Config</summary>\n\n```json\n%s\n```\n\n</details>\n\n", configData)
+
+	multiDir := len(results.Config.TargetDirectory) > 1
+	if multiDir {
+		fmt.Fprintf(f, "| Directory | Pattern | MB/s | Files/s | p99 (ms) |\n")
+		fmt.Fprintf(f, "|---|---|---:|---:|---:|\n")
+	} else {
+		fmt.Fprintf(f, "| Pattern | MB/s | Files/s | p99 (ms) |\n")
+		fmt.Fprintf(f, "|---|---:|---:|---:|\n")
+	}
+	for _, result := range results.Results {
+		if multiDir {
+			fmt.Fprintf(f, "| %s ", result.Directory)
+		}
+		if result.Error != "" {
+			fmt.Fprintf(f, "| %s | FAILED | - | - |\n", result.Pattern)
+			continue
+		}
+		p99 := "-"
+		if result.P99 > 0 {
+			p99 = strconv.FormatFloat(float64(result.P99)/float64(time.Millisecond), 'f', 3, 64)
+		}
+		fmt.Fprintf(f, "| %s | %.2f | %.2f | %s |\n", result.Pattern, result.MBytesPerSec, result.ReadPerSec, p99)
+	}
+
+	return f.Close()
+}
+
+// jsonlWriter appends one result per line as each pattern completes, so a
+// crashed run still leaves the finished patterns on disk
+type jsonlWriter struct {
+	f *os.File
+}
+
+func newJSONLWriter(path string) (*jsonlWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonlWriter{f: f}, nil
+}
+
+func (w *jsonlWriter) write(result BenchmarkResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if _, err := w.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return w.f.Sync()
+}
+
+func (w *jsonlWriter) close() error {
+	return w.f.Close()
+}
+
+// readFileGz reads path, decompressing it first when it ends in .gz
+func readFileGz(path string) ([]byte, error) {
+	if !strings.HasSuffix(path, ".gz") {
+		return os.ReadFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// writeFileGz writes data to path, gzip-compressed when it ends in .gz
+func writeFileGz(path string, data []byte) error {
+	if !strings.HasSuffix(path, ".gz") {
+		return os.WriteFile(path, data, 0644)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// orderDump is one pattern's access orders as written by -dump-order
+type orderDump struct {
+	Pattern     string        `json:"pattern"`
+	Directory   string        `json:"directory"`
+	Concurrency int           `json:"concurrency"`
+	Iterations  [][]orderStep `json:"iterations"`
+	Truncated   bool          `json:"truncated,omitempty"`
+}
+
+type orderStep struct {
+	Index int    `json:"index"`
+	Path  string `json:"path"`
+}
+
+// newOrderDump resolves the file paths of orders, keeping at most limit
+// accesses in total unless limit is 0
+func newOrderDump(files []FileInfo, orders [][]int, limit int) orderDump {
+	var dump orderDump
+	kept := 0
+	for _, order := range orders {
+		if limit > 0 && kept+len(order) > limit {
+			order = order[:limit-kept]
+			dump.Truncated = true
+		}
+		steps := make([]orderStep, len(order))
+		for i, idx := range order {
+			steps[i] = orderStep{Index: idx, Path: files[idx].Path}
+		}
+		dump.Iterations = append(dump.Iterations, steps)
+		kept += len(order)
+		if dump.Truncated {
+			break
+		}
+	}
+	return dump
+}
+
+func writeOrderDump(path string, dump orderDump) error {
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// PatternFunc generates one pass of a pattern's access order as indices
+// into files, each in [0, len(files))
+type PatternFunc func(files []FileInfo, rng *rand.Rand, cfg BenchmarkConfig) []int
+
+type registeredPattern struct {
+	name        string
+	description string
+	fn          PatternFunc
+}
+
+// patternRegistry holds every pattern at the index of its ID, index 0 is
+// unused. The built-ins are in place before any init function runs.
+var patternRegistry = builtinPatterns()
+
+func builtinPatterns() []registeredPattern {
+	registry := make([]registeredPattern, PatternSizeDescending+1)
+	add := func(id int, name, description string, fn PatternFunc) {
+		registry[id] = registeredPattern{name: name, description: description, fn: fn}
+	}
+	add(PatternSequential, "Sequential", "Every file once, in index order", sequentialOrder)
+	add(PatternReverseSeq, "Reverse Sequential", "Every file once, last to first", reverseOrder)
+	add(PatternRandom, "Random", "Every file once, shuffled", randomOrder)
+	add(PatternZipfian, "Zipfian", "Zipf-distributed picks, a few files take most reads (zipfS, zipfV, zipfPermute)", zipfianOrder)
+	add(PatternLocalityBased, "Locality-Based", "Runs of neighboring files from random starting points", localityOrder)
+	add(PatternRepeatedAccess, "Repeated Access", "A hot set takes most reads (hotSetPercent, hotSetHitRate)", hotSetOrder)
+	add(PatternWriteSequential, "Write Sequential", "Rewrites every file in index order", sequentialOrder)
+	add(PatternWriteRandom, "Write Random", "Rewrites every file in shuffled order", randomOrder)
+	add(PatternGaussian, "Gaussian", "Normally distributed picks around a center moving from the middle file (gaussianStdDev, gaussianDrift)", gaussianOrder)
+	add(PatternStrided, "Strided", "Every stride-th file, shifting the start until all are read", stridedOrder)
+	add(PatternMixed, "Mixed", "Shuffled reads with a writeRatio share of overwrites", randomOrder)
+	add(PatternRecencyDecay, "Recency Decay", "Recently read files are likely to be read again (recencyDecay)", recencyDecayOrder)
+	add(PatternTraceReplay, "Trace Replay", "Replays the order recorded in traceFile", traceReplayOrder)
+	add(PatternBimodal, "Bimodal", "Switches between two hot sets with occasional cold reads", bimodalOrder)
+	add(PatternSizeAscending, "Size Ascending", "Every file once, smallest first", sizeOrder(false))
+	add(PatternSizeDescending, "Size Descending", "Every file once, largest first", sizeOrder(true))
+	return registry
+}
+
+// RegisterPattern adds a read pattern under the next free ID and returns
+// that ID. Call it from an init function so the pattern can be picked with
+// -patterns or readPatterns like a built-in one, description is what
+// -list-patterns shows for it. It panics if the name is already taken.
+func RegisterPattern(name, description string, fn PatternFunc) int {
+	for id, p := range patternRegistry {
+		if id > 0 && patternKey(p.name) == patternKey(name) {
+			panic(fmt.Sprintf("pattern %q is already registered as ID %d", name, id))
+		}
+	}
+	patternRegistry = append(patternRegistry, registeredPattern{name: name, description: description, fn: fn})
+	return len(patternRegistry) - 1
+}
+
+func isKnownPattern(patternID int) bool {
+	return patternID >= PatternSequential && patternID < len(patternRegistry)
+}
+
+func createAccessPattern(files []FileInfo, patternID int, rng *rand.Rand, config BenchmarkConfig) []int {
+	if !isKnownPattern(patternID) {
+		return sequentialOrder(files, rng, config)
+	}
+	return patternRegistry[patternID].fn(files, rng, config)
+}
+
+// printPatterns lists the known patterns for -list-patterns
+func printPatterns() {
+	for id := PatternSequential; isKnownPattern(id); id++ {
+		fmt.Printf("%3d  %-20s %s\n", id, getPatternName(id), getPatternDescription(id))
+	}
+}
+
+func getPatternDescription(patternID int) string {
+	if !isKnownPattern(patternID) {
+		return ""
+	}
+	return patternRegistry[patternID].description
+}
+
+func getPatternName(patternID int) string {
+	if !isKnownPattern(patternID) {
+		return fmt.Sprintf("Unknown Pattern %d", patternID)
+	}
+	return patternRegistry[patternID].name
+}
+
+// localityGroupSize is the number of neighboring files the Locality-Based
+// pattern reads before jumping elsewhere
+const localityGroupSize = 5
+
+// recencyListMax caps how many recently accessed files the Recency Decay
+// pattern tracks, older ranks carry negligible weight
+const recencyListMax = 256
+
+func sequentialOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	indices := make([]int, len(files))
+	for i := range indices {
+		indices[i] = i
+	}
+	return indices
+}
+
+func reverseOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	n := len(files)
+	indices := make([]int, n)
+	for i := 0; i < n; i++ {
+		indices[i] = n - 1 - i
+	}
+	return indices
+}
+
+func randomOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	indices := sequentialOrder(files, rng, config)
+	rng.Shuffle(len(indices), func(i, j int) {
+		indices[i], indices[j] = indices[j], indices[i]
+	})
+	return indices
+}
+
+// zipfianOrder picks files from a Zipfian distribution, so some files are
+// accessed much more frequently. Rank k is file k unless ZipfPermute is set.
+func zipfianOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	n := len(files)
+	indices := make([]int, n)
+	zipf := rand.NewZipf(rng, config.ZipfS, max(config.ZipfV, 1), uint64(n-1))
+	var ranks []int
+	if config.ZipfPermute {
+		ranks = rand.New(rand.NewSource(config.zipfSeed)).Perm(n)
+	}
+	for i := 0; i < n; i++ {
+		indices[i] = int(zipf.Uint64())
+		if ranks != nil {
+			indices[i] = ranks[indices[i]]
+		}
+	}
+	return indices
+}
+
+// localityOrder reads a run of groupSize neighboring files from a random
+// base, then jumps to a new random base
+func localityOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	n := len(files)
+	indices := make([]int, n)
+	groupSize := localityGroupSize
+	if groupSize > n {
+		groupSize = n
+	}
+	for i := 0; i < n; {
+		base := rng.Intn(n - groupSize + 1)
+		for j := 0; j < groupSize && i < n; j++ {
+			indices[i] = base + j
+			i++
+		}
+	}
+	return indices
+}
+
+// hotSetOrder sends HotSetHitRate% of accesses to a hot set made of the
+// first HotSetPercent% of files
+func hotSetOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	n := len(files)
+	indices := make([]int, n)
+	hotSetSize := int(float64(n) * config.HotSetPercent / 100)
+	if hotSetSize < 1 {
+		hotSetSize = 1
+	}
+
+	hotSet := make([]int, hotSetSize)
+	for i := 0; i < hotSetSize; i++ {
+		hotSet[i] = i
+	}
+
+	for i := 0; i < n; i++ {
+		if rng.Float64()*100 < config.HotSetHitRate {
+			indices[i] = hotSet[rng.Intn(hotSetSize)]
+		} else {
+			indices[i] = rng.Intn(n)
+		}
+	}
+	return indices
+}
+
+// bimodalOrder bounces between the two hot sets, with the odd cold read
+// anywhere
+func bimodalOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	n := len(files)
+	indices := make([]int, n)
+	var lo, size [2]int
+	for s, set := range [2][2]float64{config.BimodalSetA, config.BimodalSetB} {
+		lo[s] = min(int(set[0]/100*float64(n)), n-1)
+		size[s] = max(int(set[1]/100*float64(n))-lo[s], 1)
+		size[s] = min(size[s], n-lo[s])
+	}
+	active := 0
+	for i := 0; i < n; i++ {
+		if rng.Float64() < config.BimodalSwitch {
+			active ^= 1
+		}
+		if rng.Float64() < config.BimodalCold {
+			indices[i] = rng.Intn(n)
+		} else {
+			indices[i] = lo[active] + rng.Intn(size[active])
+		}
+	}
+	return indices
+}
+
+// sizeOrder reads every file smallest or largest first, equal sizes stay
+// in index order
+func sizeOrder(descending bool) PatternFunc {
+	return func(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+		indices := sequentialOrder(files, rng, config)
+		sort.SliceStable(indices, func(a, b int) bool {
+			if descending {
+				return files[indices[a]].Size > files[indices[b]].Size
+			}
+			return files[indices[a]].Size < files[indices[b]].Size
+		})
+		return indices
+	}
+}
+
+// stridedOrder reads every Stride-th file, starting again one further along
+// each phase so every file is read exactly once
+func stridedOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	n := len(files)
+	indices := make([]int, n)
+	i := 0
+	for phase := 0; phase < config.Stride && phase < n; phase++ {
+		for idx := phase; idx < n; idx += config.Stride {
+			indices[i] = idx
+			i++
+		}
+	}
+	return indices
+}
+
+// recencyDecayOrder re-accesses the file at recency rank k with weight
+// decay^(k+1), or a uniformly random file with weight 1, so the working set
+// drifts
+func recencyDecayOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	n := len(files)
+	indices := make([]int, n)
+	decay := config.RecencyDecay
+	var recent []int
+	for i := 0; i < n; i++ {
+		total := 1.0
+		w := 1.0
+		for range recent {
+			w *= decay
+			total += w
+		}
+
+		pick := rng.Float64() * total
+		rank := -1
+		w = 1.0
+		for k := range recent {
+			w *= decay
+			if pick < w {
+				rank = k
+				break
+			}
+			pick -= w
+		}
+
+		idx := rng.Intn(n)
+		if rank >= 0 {
+			idx = recent[rank]
+			recent = append(recent[:rank], recent[rank+1:]...)
+		} else if k := slices.Index(recent, idx); k >= 0 {
+			recent = append(recent[:k], recent[k+1:]...)
+		}
+		recent = append([]int{idx}, recent...)
+		if len(recent) > recencyListMax {
+			recent = recent[:recencyListMax]
+		}
+		indices[i] = idx
+	}
+	return indices
+}
+
+// traceReplayOrder is the recorded order, looped until it covers a full
+// pass. Longer traces are replayed whole.
+func traceReplayOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	if len(config.traceOrder) == 0 {
+		return sequentialOrder(files, rng, config)
+	}
+	indices := make([]int, max(len(files), len(config.traceOrder)))
+	for i := range indices {
+		indices[i] = config.traceOrder[i%len(config.traceOrder)]
+	}
+	return indices
+}
+
+// gaussianOrder picks files from a normal distribution around a center that
+// starts at the middle of the file set and moves GaussianDrift files per
+// access. A moving center wraps around the file set and so do the picks
+// around it, a fixed one has the picks clamped to the set.
+func gaussianOrder(files []FileInfo, rng *rand.Rand, config BenchmarkConfig) []int {
+	n := len(files)
+	indices := make([]int, n)
+	stdDev := config.GaussianStdDev
+	if stdDev <= 0 {
+		stdDev = float64(n) / 6
+	}
+	for i := 0; i < n; i++ {
+		center := float64(n)/2 + config.GaussianDrift*float64(i)
+		idx := int(math.Floor(center + rng.NormFloat64()*stdDev))
+		if config.GaussianDrift != 0 {
+			idx = ((idx % n) + n) % n
+		}
+		indices[i] = min(max(idx, 0), n-1)
+	}
+	return indices
+}
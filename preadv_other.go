@@ -0,0 +1,17 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"runtime"
+)
+
+const preadvSupported = false
+
+func newPreadvReadOp(iovecSize int64, verify bool, calls *syscallCounter) (fileOp, *int64) {
+	op := func(file FileInfo) (int64, error) {
+		return 0, errors.New("preadv backend is unsupported on " + runtime.GOOS)
+	}
+	return op, nil
+}
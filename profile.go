@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+)
+
+// profiler captures CPU profiles and execution traces of the measured
+// iterations, and a heap profile once they're done. A CPU profile can't be
+// paused, so each measured iteration gets its own profile and trace, started
+// right before it and stopped right after: setup, warmup, isolation and
+// cleanup stay out of them. The files are named after cpuPath and tracePath
+// with .<pattern>.<n> inserted before the extension, go tool pprof merges
+// several given together.
+//
+// With only set, just that pattern's measured iterations are captured.
+type profiler struct {
+	cpuPath   string
+	memPath   string
+	tracePath string
+	only      string
+
+	// runs counts the profiled iterations of each pattern
+	runs map[string]int
+}
+
+func newProfiler(cpuPath, memPath, tracePath, only string) *profiler {
+	if cpuPath == "" && memPath == "" && tracePath == "" {
+		return nil
+	}
+	return &profiler{cpuPath: cpuPath, memPath: memPath, tracePath: tracePath, only: only, runs: make(map[string]int)}
+}
+
+// iterationPath inserts suffix before path's extension
+func iterationPath(path, suffix string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + suffix + ext
+}
+
+// measure runs fn as a measured iteration of the named pattern
+func (p *profiler) measure(pattern string, fn func()) {
+	if p == nil || p.only != "" && pattern != p.only || p.cpuPath == "" && p.tracePath == "" {
+		fn()
+		return
+	}
+	p.runs[pattern]++
+	suffix := fmt.Sprintf("%s.%d", patternKey(pattern), p.runs[pattern])
+
+	var cpuFile, traceFile *os.File
+	if p.cpuPath != "" {
+		f, err := os.Create(iterationPath(p.cpuPath, suffix))
+		if err != nil {
+			errorf("Error creating CPU profile: %v\n", err)
+			p.cpuPath = ""
+		} else if err := pprof.StartCPUProfile(f); err != nil {
+			errorf("Error starting CPU profile: %v\n", err)
+			f.Close()
+			p.cpuPath = ""
+		} else {
+			cpuFile = f
+		}
+	}
+	if p.tracePath != "" {
+		f, err := os.Create(iterationPath(p.tracePath, suffix))
+		if err != nil {
+			errorf("Error creating trace: %v\n", err)
+			p.tracePath = ""
+		} else if err := trace.Start(f); err != nil {
+			errorf("Error starting trace: %v\n", err)
+			f.Close()
+			p.tracePath = ""
+		} else {
+			traceFile = f
+		}
+	}
+
+	labels := pprof.Labels("pattern", pattern)
+	pprof.Do(context.Background(), labels, func(ctx context.Context) {
+		trace.WithRegion(ctx, "measured "+pattern, fn)
+	})
+
+	if traceFile != nil {
+		trace.Stop()
+		traceFile.Close()
+		verbosef("  Execution trace written to %s\n", traceFile.Name())
+	}
+	if cpuFile != nil {
+		pprof.StopCPUProfile()
+		cpuFile.Close()
+		verbosef("  CPU profile written to %s\n", cpuFile.Name())
+	}
+}
+
+// finish reports the profiles written and writes the heap profile
+func (p *profiler) finish() {
+	if p == nil {
+		return
+	}
+	var total int
+	for _, n := range p.runs {
+		total += n
+	}
+	if total > 0 && p.cpuPath != "" {
+		logf("CPU profiles of %d measured iterations written to %s (go tool pprof merges them)\n", total, iterationPath(p.cpuPath, "<pattern>.<n>"))
+	}
+	if total > 0 && p.tracePath != "" {
+		logf("Execution traces of %d measured iterations written to %s (view with go tool trace)\n", total, iterationPath(p.tracePath, "<pattern>.<n>"))
+	}
+
+	if p.memPath != "" {
+		f, err := os.Create(p.memPath)
+		if err != nil {
+			errorf("Error creating memory profile: %v\n", err)
+			return
+		}
+		defer f.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			errorf("Error writing memory profile: %v\n", err)
+			return
+		}
+		logf("Memory profile written to %s\n", p.memPath)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pushGateway pushes per-result gauges to a Prometheus push gateway. Each
+// result gets its own grouping key so patterns, sweep points and runs don't
+// overwrite each other.
+type pushGateway struct {
+	url      string
+	hostname string
+	client   *http.Client
+}
+
+func newPushGateway(url, hostname string) *pushGateway {
+	return &pushGateway{
+		url:      strings.TrimRight(url, "/"),
+		hostname: hostname,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *pushGateway) push(result BenchmarkResult) error {
+	var body bytes.Buffer
+	gauge := func(name string, value float64) {
+		fmt.Fprintf(&body, "# TYPE quark_bench_%s gauge\nquark_bench_%s %g\n", name, name, value)
+	}
+	gauge("mbytes_per_sec", result.MBytesPerSec)
+	gauge("reads_per_sec", result.ReadPerSec)
+	gauge("duration_seconds", result.Duration.Seconds())
+
+	// Label values may contain '/' or spaces so they are base64 encoded
+	// as the push gateway's grouping key syntax allows
+	url := p.url + "/metrics/job/quark_bench" +
+		groupingLabel("hostname", p.hostname) +
+		groupingLabel("pattern", result.Pattern) +
+		groupingLabel("directory", result.Directory) +
+		groupingLabel("backend", result.Backend) +
+		groupingLabel("workers", strconv.Itoa(result.Concurrency))
+	if result.BlockSizeKB > 0 {
+		url += groupingLabel("block_size_kb", strconv.Itoa(result.BlockSizeKB))
+	}
+	if result.Run > 0 {
+		url += groupingLabel("run", strconv.Itoa(result.Run))
+	}
+
+	resp, err := p.client.Post(url, "text/plain; version=0.0.4", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("push gateway returned %s", resp.Status)
+	}
+	return nil
+}
+
+func groupingLabel(name, value string) string {
+	if value == "" {
+		return "/" + name + "@base64/="
+	}
+	return "/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+}
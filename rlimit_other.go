@@ -0,0 +1,7 @@
+//go:build !unix
+
+package main
+
+func softOpenFileLimit() (uint64, bool) {
+	return 0, false
+}
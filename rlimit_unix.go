@@ -0,0 +1,14 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// softOpenFileLimit returns the soft RLIMIT_NOFILE of the process
+func softOpenFileLimit() (uint64, bool) {
+	var rlim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlim); err != nil {
+		return 0, false
+	}
+	return uint64(rlim.Cur), true
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+	"math/rand"
+	"time"
+)
+
+// accessSeq is an iteration's access order. The base order the pattern
+// generated is held in full, only its ReadsPerFile repeats are expanded on
+// the fly.
+type accessSeq struct {
+	base        []int
+	repeats     int
+	interleaved bool
+}
+
+// newAccessSeq wraps an already expanded order
+func newAccessSeq(order []int) accessSeq {
+	return accessSeq{base: order, repeats: 1}
+}
+
+func (s accessSeq) len() int {
+	return len(s.base) * s.repeats
+}
+
+// at returns access i, which must be below len
+func (s accessSeq) at(i int) int {
+	if s.interleaved {
+		return s.base[i%len(s.base)]
+	}
+	return s.base[i/s.repeats]
+}
+
+// expand materializes up to limit accesses, or all of them if limit is 0
+func (s accessSeq) expand(limit int) []int {
+	n := s.len()
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = s.at(i)
+	}
+	return order
+}
+
+// buildAccessSeq is buildAccessOrder, leaving the repeats unexpanded when
+// streaming
+func buildAccessSeq(files []FileInfo, patternID int, rng *rand.Rand, config BenchmarkConfig) accessSeq {
+	if !config.Streaming {
+		return newAccessSeq(buildAccessOrder(files, patternID, rng, config))
+	}
+	order := createAccessPattern(files, patternID, rng, config)
+	order = sampleAccessOrder(order, config.SampleSize, rng)
+	return accessSeq{base: order, repeats: max(config.ReadsPerFile, 1), interleaved: config.RepeatMode == "interleaved"}
+}
+
+// histSubBits sets the histogram's resolution to 1/2^histSubBits, under 1%
+const histSubBits = 7
+
+const histSub = 1 << histSubBits
+
+// latencyHistogram counts latencies in log-linear buckets, HDR histogram
+// style: exact below histSub nanoseconds, then histSub buckets per power of
+// two. Memory stays the same however many latencies it holds.
+type latencyHistogram struct {
+	counts []uint64
+	total  uint64
+	max    time.Duration
+}
+
+func histIndex(v uint64) int {
+	if v < histSub {
+		return int(v)
+	}
+	shift := bits.Len64(v) - histSubBits - 1
+	return (shift+1)*histSub + int(v>>shift) - histSub
+}
+
+// histValue is the highest latency that lands in bucket i
+func histValue(i int) time.Duration {
+	if i < histSub {
+		return time.Duration(i)
+	}
+	shift := i/histSub - 1
+	mantissa := uint64(i%histSub + histSub)
+	return time.Duration((mantissa+1)<<shift - 1)
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	v := uint64(max(d, 0))
+	i := histIndex(v)
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	h.total++
+	h.max = max(h.max, d)
+}
+
+func (h *latencyHistogram) merge(other *latencyHistogram) {
+	if other == nil {
+		return
+	}
+	if len(other.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, len(other.counts)-len(h.counts))...)
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.total += other.total
+	h.max = max(h.max, other.max)
+}
+
+// percentile is the nearest-rank p-th percentile like percentile, to
+// within the bucket resolution. The maximum is exact.
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(max(math.Ceil(p/100*float64(h.total)), 1))
+	if rank >= h.total {
+		return h.max
+	}
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return min(histValue(i), h.max)
+		}
+	}
+	return h.max
+}
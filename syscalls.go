@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// syscallKind is a category of syscall the measured ops make
+type syscallKind int
+
+const (
+	sysOpen  syscallKind = iota
+	sysRead              // read, pread and preadv
+	sysWrite             // write
+	sysClose
+	sysStat       // fstat
+	sysMmap       // mmap and munmap
+	sysURingEnter // io_uring_enter, however many reads it submits
+	sysOther      // fadvise, fsync and ftruncate
+	numSyscallKinds
+)
+
+// syscallCounter counts the syscalls the measured ops make, at the call
+// sites so the rest of the process's I/O (logging, background writers,
+// other goroutines) stays out of it. Calls the Go runtime makes on its own,
+// like registering an opened file with the poller, and retries after EINTR
+// aren't counted, nor is setting up an io_uring. A nil counter counts
+// nothing.
+type syscallCounter struct {
+	counts [numSyscallKinds]int64
+}
+
+func (c *syscallCounter) add(kind syscallKind, n int64) {
+	if c != nil {
+		atomic.AddInt64(&c.counts[kind], n)
+	}
+}
+
+func (c *syscallCounter) merge(other *syscallCounter) {
+	if other == nil {
+		return
+	}
+	for kind, n := range other.counts {
+		c.counts[kind] += n
+	}
+}
+
+func (c *syscallCounter) total() int64 {
+	var total int64
+	for _, n := range c.counts {
+		total += n
+	}
+	return total
+}
+
+// SyscallCounts is a syscallCounter's totals as reported in the results
+type SyscallCounts struct {
+	Open       int64 `json:"open"`
+	Read       int64 `json:"read"`
+	Write      int64 `json:"write,omitempty"`
+	Close      int64 `json:"close"`
+	Stat       int64 `json:"fstat,omitempty"`
+	Mmap       int64 `json:"mmap,omitempty"`
+	URingEnter int64 `json:"ioUringEnter,omitempty"`
+	Other      int64 `json:"other,omitempty"`
+}
+
+func (c *syscallCounter) totals() *SyscallCounts {
+	return &SyscallCounts{
+		Open:       c.counts[sysOpen],
+		Read:       c.counts[sysRead],
+		Write:      c.counts[sysWrite],
+		Close:      c.counts[sysClose],
+		Stat:       c.counts[sysStat],
+		Mmap:       c.counts[sysMmap],
+		URingEnter: c.counts[sysURingEnter],
+		Other:      c.counts[sysOther],
+	}
+}
+
+// String lists the kinds that were called, e.g. "100 open, 200 read, 100 close"
+func (s *SyscallCounts) String() string {
+	var parts []string
+	for _, kind := range []struct {
+		name string
+		n    int64
+	}{{"open", s.Open}, {"read", s.Read}, {"write", s.Write}, {"close", s.Close}, {"fstat", s.Stat}, {"mmap/munmap", s.Mmap}, {"io_uring_enter", s.URingEnter}, {"other", s.Other}} {
+		if kind.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", kind.n, kind.name))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// countedFile is an *os.File whose syscalls are counted
+type countedFile struct {
+	*os.File
+	calls *syscallCounter
+}
+
+func openCounted(path string, flag int, perm os.FileMode, calls *syscallCounter) (*countedFile, error) {
+	f, err := os.OpenFile(path, flag, perm)
+	calls.add(sysOpen, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &countedFile{File: f, calls: calls}, nil
+}
+
+func (f *countedFile) Read(b []byte) (int, error) {
+	f.calls.add(sysRead, 1)
+	return f.File.Read(b)
+}
+
+// ReadAt counts the preads os.File.ReadAt makes: one, or two when it stops
+// at the end of the file partway through b and the second returns nothing
+func (f *countedFile) ReadAt(b []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(b, off)
+	f.calls.add(sysRead, 1)
+	if err == io.EOF && n > 0 {
+		f.calls.add(sysRead, 1)
+	}
+	return n, err
+}
+
+func (f *countedFile) Write(b []byte) (int, error) {
+	f.calls.add(sysWrite, 1)
+	return f.File.Write(b)
+}
+
+func (f *countedFile) Stat() (os.FileInfo, error) {
+	f.calls.add(sysStat, 1)
+	return f.File.Stat()
+}
+
+func (f *countedFile) Sync() error {
+	f.calls.add(sysOther, 1)
+	return f.File.Sync()
+}
+
+func (f *countedFile) Truncate(size int64) error {
+	f.calls.add(sysOther, 1)
+	return f.File.Truncate(size)
+}
+
+func (f *countedFile) fadvise(advice int) error {
+	f.calls.add(sysOther, 1)
+	return fadvise(f.File, advice)
+}
+
+func (f *countedFile) Close() error {
+	f.calls.add(sysClose, 1)
+	return f.File.Close()
+}
+
+// readCounted reads the whole file the way os.ReadFile does
+func readCounted(path string, calls *syscallCounter) ([]byte, error) {
+	f, err := openCounted(path, os.O_RDONLY, 0, calls)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	size := 512
+	if info, err := f.Stat(); err == nil && int64(int(info.Size())) == info.Size() {
+		size += int(info.Size())
+	}
+	data := make([]byte, 0, size)
+	for {
+		n, err := f.Read(data[len(data):cap(data)])
+		data = data[:len(data)+n]
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return data, err
+		}
+		if len(data) >= cap(data) {
+			d := append(data[:cap(data)], 0)
+			data = d[:len(data)]
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+func totalMemoryBytes() uint64 {
+	return 0
+}
+
+func cpuModel() string {
+	return ""
+}
@@ -0,0 +1,36 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+const tmpfsSupported = true
+
+const tmpfsMagic = 0x01021994
+
+// isTmpfs reports whether dir is on a tmpfs
+func isTmpfs(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return false
+	}
+	return int64(st.Type) == tmpfsMagic
+}
+
+// mountTmpfs mounts a tmpfs over dir, sized by the kernel default of half
+// the RAM
+func mountTmpfs(dir string) error {
+	err := syscall.Mount("tmpfs", dir, "tmpfs", 0, "mode=0755")
+	if errors.Is(err, syscall.EPERM) {
+		return fmt.Errorf("mounting a tmpfs on %s needs root, mount one yourself (mount -t tmpfs tmpfs %s) and rerun: %w", dir, dir, err)
+	}
+	return err
+}
+
+func unmountTmpfs(dir string) error {
+	return syscall.Unmount(dir, 0)
+}
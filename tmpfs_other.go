@@ -0,0 +1,22 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"runtime"
+)
+
+const tmpfsSupported = false
+
+func isTmpfs(dir string) bool {
+	return false
+}
+
+func mountTmpfs(dir string) error {
+	return errors.New("tmpfs is unsupported on " + runtime.GOOS)
+}
+
+func unmountTmpfs(dir string) error {
+	return errors.New("tmpfs is unsupported on " + runtime.GOOS)
+}